@@ -3,9 +3,11 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/dukerupert/weekend-warrior/db/models"
+	"github.com/jackc/pgx/v5"
 )
 
 // CreateController creates a new controller in the database
@@ -15,7 +17,7 @@ func (s *Service) CreateController(ctx context.Context, params models.CreateCont
 	err := s.pool.QueryRow(ctx, `
         INSERT INTO controllers (name, initials, email, facility_id)
         VALUES ($1, $2, $3, $4)
-        RETURNING id, created_at, name, initials, email, facility_id
+        RETURNING id, created_at, name, initials, email, facility_id, archived_at, version
     `, params.Name, params.Initials, params.Email, params.FacilityID).Scan(
 		&controller.ID,
 		&controller.CreatedAt,
@@ -23,6 +25,8 @@ func (s *Service) CreateController(ctx context.Context, params models.CreateCont
 		&controller.Initials,
 		&controller.Email,
 		&controller.FacilityID,
+		&controller.ArchivedAt,
+		&controller.Version,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("error creating controller: %w", err)
@@ -31,12 +35,12 @@ func (s *Service) CreateController(ctx context.Context, params models.CreateCont
 	return &controller, nil
 }
 
-// GetControllerByID retrieves a controller by its ID
+// GetControllerByID retrieves a controller by its ID, including archived controllers
 func (s *Service) GetControllerByID(ctx context.Context, id int) (*models.Controller, error) {
 	var controller models.Controller
 
 	err := s.pool.QueryRow(ctx, `
-        SELECT id, created_at, name, initials, email, facility_id
+        SELECT id, created_at, name, initials, email, facility_id, archived_at, version
         FROM controllers
         WHERE id = $1
     `, id).Scan(
@@ -46,6 +50,8 @@ func (s *Service) GetControllerByID(ctx context.Context, id int) (*models.Contro
 		&controller.Initials,
 		&controller.Email,
 		&controller.FacilityID,
+		&controller.ArchivedAt,
+		&controller.Version,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("error getting controller: %w", err)
@@ -54,14 +60,40 @@ func (s *Service) GetControllerByID(ctx context.Context, id int) (*models.Contro
 	return &controller, nil
 }
 
+// GetControllerByEmail retrieves an active controller by email address
+func (s *Service) GetControllerByEmail(ctx context.Context, email string) (*models.Controller, error) {
+	var controller models.Controller
+
+	err := s.pool.QueryRow(ctx, `
+        SELECT id, created_at, name, initials, email, facility_id, archived_at, version
+        FROM controllers
+        WHERE email = $1 AND archived_at IS NULL
+    `, email).Scan(
+		&controller.ID,
+		&controller.CreatedAt,
+		&controller.Name,
+		&controller.Initials,
+		&controller.Email,
+		&controller.FacilityID,
+		&controller.ArchivedAt,
+		&controller.Version,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error getting controller by email: %w", err)
+	}
+
+	return &controller, nil
+}
+
 // GetControllersByFacility retrieves all controllers for a facility
-func (s *Service) GetControllersByFacility(ctx context.Context, facilityID int) ([]models.Controller, error) {
+func (s *Service) GetControllersByFacility(ctx context.Context, facilityID int, params models.ListControllersParams) ([]models.Controller, error) {
 	rows, err := s.pool.Query(ctx, `
-        SELECT id, created_at, name, initials, email, facility_id
+        SELECT id, created_at, name, initials, email, facility_id, archived_at, version
         FROM controllers
         WHERE facility_id = $1
+          AND ($2 OR archived_at IS NULL)
         ORDER BY name ASC
-    `, facilityID)
+    `, facilityID, params.IncludeArchived)
 	if err != nil {
 		return nil, fmt.Errorf("error listing controllers: %w", err)
 	}
@@ -77,6 +109,8 @@ func (s *Service) GetControllersByFacility(ctx context.Context, facilityID int)
 			&controller.Initials,
 			&controller.Email,
 			&controller.FacilityID,
+			&controller.ArchivedAt,
+			&controller.Version,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning controller row: %w", err)
@@ -91,13 +125,14 @@ func (s *Service) GetControllersByFacility(ctx context.Context, facilityID int)
 	return controllers, nil
 }
 
-// ListControllers retrieves all controllers
-func (s *Service) ListControllers(ctx context.Context) ([]models.Controller, error) {
+// ListControllers retrieves all controllers, hiding archived controllers unless requested
+func (s *Service) ListControllers(ctx context.Context, params models.ListControllersParams) ([]models.Controller, error) {
 	rows, err := s.pool.Query(ctx, `
-        SELECT id, created_at, name, initials, email, facility_id
+        SELECT id, created_at, name, initials, email, facility_id, archived_at, version
         FROM controllers
+        WHERE $1 OR archived_at IS NULL
         ORDER BY name ASC
-    `)
+    `, params.IncludeArchived)
 	if err != nil {
 		return nil, fmt.Errorf("error listing controllers: %w", err)
 	}
@@ -113,6 +148,8 @@ func (s *Service) ListControllers(ctx context.Context) ([]models.Controller, err
 			&controller.Initials,
 			&controller.Email,
 			&controller.FacilityID,
+			&controller.ArchivedAt,
+			&controller.Version,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning controller row: %w", err)
@@ -127,38 +164,45 @@ func (s *Service) ListControllers(ctx context.Context) ([]models.Controller, err
 	return controllers, nil
 }
 
-// UpdateController updates an existing controller
-func (s *Service) UpdateController(ctx context.Context, id int, params models.CreateControllerParams) (*models.Controller, error) {
+// UpdateController updates an existing controller if it is still at the given
+// version. ErrStaleVersion is returned when another update got there first.
+func (s *Service) UpdateController(ctx context.Context, id int, version int, params models.CreateControllerParams) (*models.Controller, error) {
 	var controller models.Controller
 
 	err := s.pool.QueryRow(ctx, `
         UPDATE controllers
-        SET name = $1, initials = $2, email = $3, facility_id = $4
-        WHERE id = $5
-        RETURNING id, created_at, name, initials, email, facility_id
-    `, params.Name, params.Initials, params.Email, params.FacilityID, id).Scan(
+        SET name = $1, initials = $2, email = $3, facility_id = $4, version = version + 1
+        WHERE id = $5 AND version = $6
+        RETURNING id, created_at, name, initials, email, facility_id, archived_at, version
+    `, params.Name, params.Initials, params.Email, params.FacilityID, id, version).Scan(
 		&controller.ID,
 		&controller.CreatedAt,
 		&controller.Name,
 		&controller.Initials,
 		&controller.Email,
 		&controller.FacilityID,
+		&controller.ArchivedAt,
+		&controller.Version,
 	)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, s.staleOrMissing(ctx, "controllers", id)
+		}
 		return nil, fmt.Errorf("error updating controller: %w", err)
 	}
 
 	return &controller, nil
 }
 
-// DeleteController deletes a controller by ID
-func (s *Service) DeleteController(ctx context.Context, id int) error {
+// ArchiveController marks a controller as archived, keeping its schedule and role history
+func (s *Service) ArchiveController(ctx context.Context, id int) error {
 	result, err := s.pool.Exec(ctx, `
-        DELETE FROM controllers
-        WHERE id = $1
+        UPDATE controllers
+        SET archived_at = CURRENT_TIMESTAMP, version = version + 1
+        WHERE id = $1 AND archived_at IS NULL
     `, id)
 	if err != nil {
-		return fmt.Errorf("error deleting controller: %w", err)
+		return fmt.Errorf("error archiving controller: %w", err)
 	}
 
 	if result.RowsAffected() == 0 {
@@ -167,3 +211,70 @@ func (s *Service) DeleteController(ctx context.Context, id int) error {
 
 	return nil
 }
+
+// RestoreController clears the archived flag on a controller
+func (s *Service) RestoreController(ctx context.Context, id int) (*models.Controller, error) {
+	var controller models.Controller
+
+	err := s.pool.QueryRow(ctx, `
+        UPDATE controllers
+        SET archived_at = NULL, version = version + 1
+        WHERE id = $1 AND archived_at IS NOT NULL
+        RETURNING id, created_at, name, initials, email, facility_id, archived_at, version
+    `, id).Scan(
+		&controller.ID,
+		&controller.CreatedAt,
+		&controller.Name,
+		&controller.Initials,
+		&controller.Email,
+		&controller.FacilityID,
+		&controller.ArchivedAt,
+		&controller.Version,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error restoring controller: %w", err)
+	}
+
+	return &controller, nil
+}
+
+// PurgeController permanently deletes an archived controller along with
+// its schedule and role assignments
+func (s *Service) PurgeController(ctx context.Context, id int) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting purge transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+        SELECT id
+        FROM controllers
+        WHERE id = $1 AND archived_at IS NOT NULL
+        FOR UPDATE
+    `, id)
+	if err != nil {
+		return fmt.Errorf("error locking controller: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("archived controller with ID %d not found", id)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM schedules WHERE controller_id = $1`, id); err != nil {
+		return fmt.Errorf("error purging controller schedules: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM controller_facility_roles WHERE controller_id = $1`, id); err != nil {
+		return fmt.Errorf("error purging controller roles: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM controllers WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("error purging controller: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing controller purge: %w", err)
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/jackc/pgx/v5"
@@ -11,6 +12,10 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrStaleVersion is returned when an update names a row version that is no
+// longer current
+var ErrStaleVersion = errors.New("row version is stale")
+
 type Config struct {
 	URL string
 }
@@ -20,7 +25,16 @@ type Service struct {
 }
 
 func NewService(cfg Config) (*Service, error) {
-	pool, err := pgxpool.New(context.Background(), cfg.URL)
+	poolConfig, err := pgxpool.ParseConfig(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse database URL: %w", err)
+	}
+
+	// Log queries with the request-scoped logger from each query's context
+	// and trace them as children of the request's span
+	poolConfig.ConnConfig.Tracer = tracers{queryLogger{}, newQueryTracer()}
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create connection pool: %w", err)
 	}
@@ -43,6 +57,14 @@ func (s *Service) Close() {
 	}
 }
 
+// Ping checks that a connection can be acquired and the database responds
+func (s *Service) Ping(ctx context.Context) error {
+	if err := s.pool.Ping(ctx); err != nil {
+		return fmt.Errorf("error pinging database: %w", err)
+	}
+	return nil
+}
+
 // Exec executes a SQL query without returning any rows
 func (s *Service) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
 	return s.pool.Exec(ctx, sql, arguments...)
@@ -57,3 +79,17 @@ func (s *Service) Query(ctx context.Context, sql string, args ...interface{}) (p
 func (s *Service) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
 	return s.pool.QueryRow(ctx, sql, args...)
 }
+
+// staleOrMissing explains why a versioned update matched no rows: either the
+// row does not exist or its version has moved on
+func (s *Service) staleOrMissing(ctx context.Context, table string, id int) error {
+	var exists bool
+	err := s.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM `+pgx.Identifier{table}.Sanitize()+` WHERE id = $1)`, id).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("error checking %s row: %w", table, err)
+	}
+	if exists {
+		return ErrStaleVersion
+	}
+	return fmt.Errorf("%s row with ID %d not found", table, id)
+}
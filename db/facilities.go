@@ -3,24 +3,37 @@ package db
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
 	"fmt"
 
 	"github.com/dukerupert/weekend-warrior/db/models"
+	"github.com/jackc/pgx/v5"
 )
 
 // CreateFacility creates a new facility in the database
 func (s *Service) CreateFacility(ctx context.Context, params models.CreateFacilityParams) (*models.Facility, error) {
 	var facility models.Facility
 
+	minOnDuty := models.DefaultMinOnDuty
+	if params.MinOnDuty != nil {
+		minOnDuty = *params.MinOnDuty
+	}
+
 	err := s.pool.QueryRow(ctx, `
-        INSERT INTO facilities (name, code)
-        VALUES ($1, $2)
-        RETURNING id, created_at, name, code
-    `, params.Name, params.Code).Scan(
+        INSERT INTO facilities (name, code, min_on_duty)
+        VALUES ($1, $2, $3)
+        RETURNING id, created_at, name, code, min_on_duty, archived_at, version
+    `, params.Name, params.Code, minOnDuty).Scan(
 		&facility.ID,
 		&facility.CreatedAt,
 		&facility.Name,
 		&facility.Code,
+		&facility.MinOnDuty,
+		&facility.ArchivedAt,
+		&facility.Version,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("error creating facility: %w", err)
@@ -29,12 +42,12 @@ func (s *Service) CreateFacility(ctx context.Context, params models.CreateFacili
 	return &facility, nil
 }
 
-// GetFacilityByID retrieves a facility by its ID
+// GetFacilityByID retrieves a facility by its ID, including archived facilities
 func (s *Service) GetFacilityByID(ctx context.Context, id int) (*models.Facility, error) {
 	var facility models.Facility
 
 	err := s.pool.QueryRow(ctx, `
-        SELECT id, created_at, name, code
+        SELECT id, created_at, name, code, min_on_duty, archived_at, version
         FROM facilities
         WHERE id = $1
     `, id).Scan(
@@ -42,6 +55,9 @@ func (s *Service) GetFacilityByID(ctx context.Context, id int) (*models.Facility
 		&facility.CreatedAt,
 		&facility.Name,
 		&facility.Code,
+		&facility.MinOnDuty,
+		&facility.ArchivedAt,
+		&facility.Version,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("error getting facility: %w", err)
@@ -55,7 +71,7 @@ func (s *Service) GetFacilityByCode(ctx context.Context, code string) (*models.F
 	var facility models.Facility
 
 	err := s.pool.QueryRow(ctx, `
-        SELECT id, created_at, name, code
+        SELECT id, created_at, name, code, min_on_duty, archived_at, version
         FROM facilities
         WHERE code = $1
     `, code).Scan(
@@ -63,6 +79,9 @@ func (s *Service) GetFacilityByCode(ctx context.Context, code string) (*models.F
 		&facility.CreatedAt,
 		&facility.Name,
 		&facility.Code,
+		&facility.MinOnDuty,
+		&facility.ArchivedAt,
+		&facility.Version,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("error getting facility by code: %w", err)
@@ -71,13 +90,14 @@ func (s *Service) GetFacilityByCode(ctx context.Context, code string) (*models.F
 	return &facility, nil
 }
 
-// ListFacilities retrieves all facilities from the database
-func (s *Service) ListFacilities(ctx context.Context) ([]models.Facility, error) {
+// ListFacilities retrieves all facilities, hiding archived facilities unless requested
+func (s *Service) ListFacilities(ctx context.Context, params models.ListFacilitiesParams) ([]models.Facility, error) {
 	rows, err := s.pool.Query(ctx, `
-        SELECT id, created_at, name, code
+        SELECT id, created_at, name, code, min_on_duty, archived_at, version
         FROM facilities
+        WHERE $1 OR archived_at IS NULL
         ORDER BY name ASC
-    `)
+    `, params.IncludeArchived)
 	if err != nil {
 		return nil, fmt.Errorf("error listing facilities: %w", err)
 	}
@@ -91,6 +111,9 @@ func (s *Service) ListFacilities(ctx context.Context) ([]models.Facility, error)
 			&facility.CreatedAt,
 			&facility.Name,
 			&facility.Code,
+			&facility.MinOnDuty,
+			&facility.ArchivedAt,
+			&facility.Version,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning facility row: %w", err)
@@ -105,14 +128,44 @@ func (s *Service) ListFacilities(ctx context.Context) ([]models.Facility, error)
 	return facilities, nil
 }
 
-// DeleteFacility deletes a facility by its ID
-func (s *Service) DeleteFacility(ctx context.Context, id int) error {
+// UpdateFacility updates a facility's settings if it is still at the given
+// version. ErrStaleVersion is returned when another update got there first.
+func (s *Service) UpdateFacility(ctx context.Context, id int, version int, params models.UpdateFacilityParams) (*models.Facility, error) {
+	var facility models.Facility
+
+	err := s.pool.QueryRow(ctx, `
+        UPDATE facilities
+        SET name = $1, code = $2, min_on_duty = COALESCE($3, min_on_duty), version = version + 1
+        WHERE id = $4 AND version = $5
+        RETURNING id, created_at, name, code, min_on_duty, archived_at, version
+    `, params.Name, params.Code, params.MinOnDuty, id, version).Scan(
+		&facility.ID,
+		&facility.CreatedAt,
+		&facility.Name,
+		&facility.Code,
+		&facility.MinOnDuty,
+		&facility.ArchivedAt,
+		&facility.Version,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, s.staleOrMissing(ctx, "facilities", id)
+		}
+		return nil, fmt.Errorf("error updating facility: %w", err)
+	}
+
+	return &facility, nil
+}
+
+// ArchiveFacility marks a facility as archived
+func (s *Service) ArchiveFacility(ctx context.Context, id int) error {
 	result, err := s.pool.Exec(ctx, `
-        DELETE FROM facilities
-        WHERE id = $1
+        UPDATE facilities
+        SET archived_at = CURRENT_TIMESTAMP, version = version + 1
+        WHERE id = $1 AND archived_at IS NULL
     `, id)
 	if err != nil {
-		return fmt.Errorf("error deleting facility: %w", err)
+		return fmt.Errorf("error archiving facility: %w", err)
 	}
 
 	if result.RowsAffected() == 0 {
@@ -122,19 +175,261 @@ func (s *Service) DeleteFacility(ctx context.Context, id int) error {
 	return nil
 }
 
-// DeleteFacilityByCode deletes a facility by its code
-func (s *Service) DeleteFacilityByCode(ctx context.Context, code string) error {
-	result, err := s.pool.Exec(ctx, `
-        DELETE FROM facilities
-        WHERE code = $1
-    `, code)
+// RestoreFacility clears the archived flag on a facility
+func (s *Service) RestoreFacility(ctx context.Context, id int) (*models.Facility, error) {
+	var facility models.Facility
+
+	err := s.pool.QueryRow(ctx, `
+        UPDATE facilities
+        SET archived_at = NULL, version = version + 1
+        WHERE id = $1 AND archived_at IS NOT NULL
+        RETURNING id, created_at, name, code, min_on_duty, archived_at, version
+    `, id).Scan(
+		&facility.ID,
+		&facility.CreatedAt,
+		&facility.Name,
+		&facility.Code,
+		&facility.MinOnDuty,
+		&facility.ArchivedAt,
+		&facility.Version,
+	)
 	if err != nil {
-		return fmt.Errorf("error deleting facility: %w", err)
+		return nil, fmt.Errorf("error restoring facility: %w", err)
 	}
 
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("facility with code %s not found", code)
+	return &facility, nil
+}
+
+// GetFacilityStaffing counts the active controllers at every active facility
+// and how many of them have no schedule yet
+func (s *Service) GetFacilityStaffing(ctx context.Context) ([]models.FacilityStaffing, error) {
+	rows, err := s.pool.Query(ctx, `
+        SELECT f.code,
+               COUNT(c.id),
+               COUNT(c.id) FILTER (WHERE sch.id IS NULL)
+        FROM facilities f
+        LEFT JOIN controllers c ON c.facility_id = f.id AND c.archived_at IS NULL
+        LEFT JOIN schedules sch ON sch.controller_id = c.id
+        WHERE f.archived_at IS NULL
+        GROUP BY f.code
+        ORDER BY f.code ASC
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("error getting facility staffing: %w", err)
 	}
+	defer rows.Close()
 
-	return nil
+	var staffing []models.FacilityStaffing
+	for rows.Next() {
+		var fs models.FacilityStaffing
+		if err := rows.Scan(&fs.FacilityCode, &fs.Controllers, &fs.MissingSchedules); err != nil {
+			return nil, fmt.Errorf("error scanning facility staffing row: %w", err)
+		}
+		staffing = append(staffing, fs)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating facility staffing rows: %w", err)
+	}
+
+	return staffing, nil
+}
+
+// ErrPurgeTokenMismatch is returned when a purge confirmation token no longer
+// matches the rows that would be removed
+var ErrPurgeTokenMismatch = errors.New("confirmation token does not match the current purge plan")
+
+// PreviewFacilityPurge reports the controllers, schedules and role assignments
+// that PurgeFacility would remove, without changing anything
+func (s *Service) PreviewFacilityPurge(ctx context.Context, id int) (*models.FacilityPurgePlan, error) {
+	return loadFacilityPurgePlan(ctx, s.pool, id, false)
+}
+
+// PurgeFacility permanently deletes an archived facility together with its
+// controllers, their schedules and all related role assignments. The token
+// must come from PreviewFacilityPurge; if the affected rows changed since the
+// preview, ErrPurgeTokenMismatch is returned and nothing is deleted.
+func (s *Service) PurgeFacility(ctx context.Context, id int, token string) (*models.FacilityPurgePlan, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error starting purge transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	plan, err := loadFacilityPurgePlan(ctx, tx, id, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(plan.ConfirmationToken), []byte(token)) != 1 {
+		return nil, ErrPurgeTokenMismatch
+	}
+
+	if _, err := tx.Exec(ctx, `
+        DELETE FROM schedules
+        WHERE controller_id IN (SELECT id FROM controllers WHERE facility_id = $1)
+    `, id); err != nil {
+		return nil, fmt.Errorf("error purging facility schedules: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+        DELETE FROM controller_facility_roles
+        WHERE facility_id = $1
+           OR controller_id IN (SELECT id FROM controllers WHERE facility_id = $1)
+    `, id); err != nil {
+		return nil, fmt.Errorf("error purging facility role assignments: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM controllers WHERE facility_id = $1`, id); err != nil {
+		return nil, fmt.Errorf("error purging facility controllers: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM facilities WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("error purging facility: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("error committing facility purge: %w", err)
+	}
+
+	return plan, nil
+}
+
+// queryer is satisfied by both the pool and a transaction
+type queryer interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// loadFacilityPurgePlan collects the rows affected by purging an archived
+// facility. When lock is set the facility and its controllers are locked so
+// the plan cannot change before the transaction commits.
+func loadFacilityPurgePlan(ctx context.Context, q queryer, id int, lock bool) (*models.FacilityPurgePlan, error) {
+	forUpdate := ""
+	if lock {
+		forUpdate = "FOR UPDATE"
+	}
+
+	plan := &models.FacilityPurgePlan{
+		Controllers:       []models.Controller{},
+		ScheduleIDs:       []int{},
+		RoleAssignmentIDs: []int{},
+	}
+
+	err := q.QueryRow(ctx, `
+        SELECT id, created_at, name, code, min_on_duty, archived_at, version
+        FROM facilities
+        WHERE id = $1 AND archived_at IS NOT NULL
+    `+forUpdate, id).Scan(
+		&plan.Facility.ID,
+		&plan.Facility.CreatedAt,
+		&plan.Facility.Name,
+		&plan.Facility.Code,
+		&plan.Facility.MinOnDuty,
+		&plan.Facility.ArchivedAt,
+		&plan.Facility.Version,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("archived facility with ID %d not found", id)
+		}
+		return nil, fmt.Errorf("error getting facility: %w", err)
+	}
+
+	rows, err := q.Query(ctx, `
+        SELECT id, created_at, name, initials, email, facility_id, archived_at, version
+        FROM controllers
+        WHERE facility_id = $1
+        ORDER BY id ASC
+    `+forUpdate, id)
+	if err != nil {
+		return nil, fmt.Errorf("error listing facility controllers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var controller models.Controller
+		err := rows.Scan(
+			&controller.ID,
+			&controller.CreatedAt,
+			&controller.Name,
+			&controller.Initials,
+			&controller.Email,
+			&controller.FacilityID,
+			&controller.ArchivedAt,
+			&controller.Version,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning controller row: %w", err)
+		}
+		plan.Controllers = append(plan.Controllers, controller)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating controller rows: %w", err)
+	}
+
+	plan.ScheduleIDs, err = queryIDs(ctx, q, `
+        SELECT id
+        FROM schedules
+        WHERE controller_id IN (SELECT id FROM controllers WHERE facility_id = $1)
+        ORDER BY id ASC
+    `, id)
+	if err != nil {
+		return nil, fmt.Errorf("error listing facility schedules: %w", err)
+	}
+
+	plan.RoleAssignmentIDs, err = queryIDs(ctx, q, `
+        SELECT id
+        FROM controller_facility_roles
+        WHERE facility_id = $1
+           OR controller_id IN (SELECT id FROM controllers WHERE facility_id = $1)
+        ORDER BY id ASC
+    `, id)
+	if err != nil {
+		return nil, fmt.Errorf("error listing facility role assignments: %w", err)
+	}
+
+	plan.ConfirmationToken = purgeToken(plan)
+
+	return plan, nil
+}
+
+// queryIDs runs a query returning a single integer column
+func queryIDs(ctx context.Context, q queryer, sql string, args ...interface{}) ([]int, error) {
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// purgeToken fingerprints the rows in a purge plan so a confirmation can only
+// be applied to the exact set of rows that was previewed
+func purgeToken(plan *models.FacilityPurgePlan) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "facility:%d;controllers:", plan.Facility.ID)
+	for _, controller := range plan.Controllers {
+		fmt.Fprintf(h, "%d,", controller.ID)
+	}
+	fmt.Fprint(h, ";schedules:")
+	for _, id := range plan.ScheduleIDs {
+		fmt.Fprintf(h, "%d,", id)
+	}
+	fmt.Fprint(h, ";roles:")
+	for _, id := range plan.RoleAssignmentIDs {
+		fmt.Fprintf(h, "%d,", id)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:32]
 }
@@ -0,0 +1,50 @@
+// db/logging.go
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+// queryLogger logs every query through the logger carried by the query's
+// context, so SQL issued for a request shares that request's ID
+type queryLogger struct{}
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	sql   string
+	start time.Time
+}
+
+// TraceQueryStart implements pgx.QueryTracer
+func (queryLogger) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey{}, queryStart{
+		sql:   strings.Join(strings.Fields(data.SQL), " "),
+		start: time.Now(),
+	})
+}
+
+// TraceQueryEnd implements pgx.QueryTracer
+func (queryLogger) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	started, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+
+	logger := zerolog.Ctx(ctx)
+	event := logger.Debug()
+	if data.Err != nil {
+		event = logger.Warn().Err(data.Err)
+	}
+
+	event.
+		Str("sql", started.sql).
+		Int64("rows", data.CommandTag.RowsAffected()).
+		Dur("duration", time.Since(started.start)).
+		Msg("query executed")
+}
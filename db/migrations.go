@@ -0,0 +1,63 @@
+// db/migrations.go
+package db
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// ExpectedMigrationVersion returns the version of the newest goose migration
+// shipped with this binary
+func ExpectedMigrationVersion() (int64, error) {
+	files, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return 0, fmt.Errorf("error listing migrations: %w", err)
+	}
+
+	var latest int64
+	for _, file := range files {
+		name := strings.TrimPrefix(file, "migrations/")
+		prefix, _, ok := strings.Cut(name, "_")
+		if !ok {
+			return 0, fmt.Errorf("migration %s has no version prefix", name)
+		}
+
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("migration %s has an invalid version: %w", name, err)
+		}
+		if version > latest {
+			latest = version
+		}
+	}
+
+	return latest, nil
+}
+
+// MigrationVersion returns the newest migration goose has applied to the
+// database, ignoring migrations that were rolled back
+func (s *Service) MigrationVersion(ctx context.Context) (int64, error) {
+	var version int64
+
+	err := s.pool.QueryRow(ctx, `
+        SELECT COALESCE(MAX(version_id), 0)
+        FROM (
+            SELECT DISTINCT ON (version_id) version_id, is_applied
+            FROM goose_db_version
+            ORDER BY version_id, id DESC
+        ) latest
+        WHERE is_applied
+    `).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("error getting migration version: %w", err)
+	}
+
+	return version, nil
+}
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE facilities ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP;
+ALTER TABLE controllers ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE controllers DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE facilities DROP COLUMN IF EXISTS archived_at;
+-- +goose StatementEnd
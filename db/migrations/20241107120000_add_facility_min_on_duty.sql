@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE facilities ADD COLUMN IF NOT EXISTS min_on_duty INTEGER NOT NULL DEFAULT 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE facilities DROP COLUMN IF EXISTS min_on_duty;
+-- +goose StatementEnd
@@ -5,18 +5,25 @@ import "time"
 
 // Controller represents a controller in the database
 type Controller struct {
-	ID         int       `json:"id"`
-	CreatedAt  time.Time `json:"created_at"`
-	Name       string    `json:"name"`
-	Initials   string    `json:"initials"`
-	Email      string    `json:"email"`
-	FacilityID int       `json:"facility_id"`
+	ID         int        `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Name       string     `json:"name"`
+	Initials   string     `json:"initials"`
+	Email      string     `json:"email"`
+	FacilityID int        `json:"facility_id"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+	Version    int        `json:"version"`
 }
 
 // CreateControllerParams holds the parameters needed to create a new controller
 type CreateControllerParams struct {
-	Name       string `json:"name"`
-	Initials   string `json:"initials"`
-	Email      string `json:"email"`
-	FacilityID int    `json:"facility_id"`
+	Name       string `json:"name" form:"name" validate:"required,max=100"`
+	Initials   string `json:"initials" form:"initials" validate:"len=2,alpha"`
+	Email      string `json:"email" form:"email" validate:"required,email"`
+	FacilityID int    `json:"facility_id" form:"facility_id" validate:"required,gt=0"`
+}
+
+// ListControllersParams holds the filters for listing controllers
+type ListControllersParams struct {
+	IncludeArchived bool
 }
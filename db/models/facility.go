@@ -3,16 +3,56 @@ package models
 
 import "time"
 
+// DefaultMinOnDuty is the staffing minimum of a facility created without
+// one, so a day is never traded away from its last controller on duty
+const DefaultMinOnDuty = 1
+
 // Facility represents a facility in the database
 type Facility struct {
 	ID        int       `json:"id"`
 	CreatedAt time.Time `json:"created_at"`
 	Name      string    `json:"name"`
 	Code      string    `json:"code"`
+	// MinOnDuty is how many controllers must be working a day for one of
+	// them to trade it away
+	MinOnDuty  int        `json:"min_on_duty"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+	Version    int        `json:"version"`
 }
 
 // CreateFacilityParams holds the parameters needed to create a new facility
 type CreateFacilityParams struct {
-	Name string `json:"name"`
-	Code string `json:"code"`
+	Name string `json:"name" form:"name" validate:"required,max=100"`
+	Code string `json:"code" form:"code" validate:"len=4,alphanum"`
+	// MinOnDuty defaults to DefaultMinOnDuty when nil
+	MinOnDuty *int `json:"min_on_duty" form:"min_on_duty" validate:"omitempty,min=0"`
+}
+
+// UpdateFacilityParams holds the editable settings of a facility
+type UpdateFacilityParams struct {
+	Name string `json:"name" form:"name" validate:"required,max=100"`
+	Code string `json:"code" form:"code" validate:"len=4,alphanum"`
+	// MinOnDuty is left as it is when nil
+	MinOnDuty *int `json:"min_on_duty" form:"min_on_duty" validate:"omitempty,min=0"`
+}
+
+// ListFacilitiesParams holds the filters for listing facilities
+type ListFacilitiesParams struct {
+	IncludeArchived bool
+}
+
+// FacilityPurgePlan describes every row removed when a facility is purged
+type FacilityPurgePlan struct {
+	Facility          Facility     `json:"facility"`
+	Controllers       []Controller `json:"controllers"`
+	ScheduleIDs       []int        `json:"schedule_ids"`
+	RoleAssignmentIDs []int        `json:"role_assignment_ids"`
+	ConfirmationToken string       `json:"confirmation_token"`
+}
+
+// FacilityStaffing summarises the active controllers at a facility
+type FacilityStaffing struct {
+	FacilityCode     string `json:"facility_code"`
+	Controllers      int    `json:"controllers"`
+	MissingSchedules int    `json:"missing_schedules"`
 }
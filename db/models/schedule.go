@@ -11,15 +11,26 @@ type Schedule struct {
 	RDOs         []int     `json:"rdos"`
 	Anchor       time.Time `json:"anchor"`
 	ControllerID int       `json:"controller_id"`
+	Version      int       `json:"version"`
 }
 
+// CreateScheduleParams holds the parameters needed to create a schedule.
+// RDOs are weekdays from 0 (Sunday) to 6 (Saturday); the calendar pairs the
+// first two, so at least two are required.
 type CreateScheduleParams struct {
-	RDOs         []int     `json:"rdos"`
-	Anchor       time.Time `json:"anchor"`
-	ControllerID int       `json:"controller_id"`
+	RDOs         []int     `json:"rdos" validate:"required,min=2,max=7,unique,dive,min=0,max=6"`
+	Anchor       time.Time `json:"anchor" validate:"required"`
+	ControllerID int       `json:"controller_id" validate:"required,gt=0"`
 }
 
+// UpdateScheduleParams holds the editable fields of a schedule
 type UpdateScheduleParams struct {
-	RDOs   []int     `json:"rdos"`
-	Anchor time.Time `json:"anchor"`
+	RDOs   []int     `json:"rdos" validate:"required,min=2,max=7,unique,dive,min=0,max=6"`
+	Anchor time.Time `json:"anchor" validate:"required"`
+}
+
+// ControllerSchedule pairs an active controller with their schedule
+type ControllerSchedule struct {
+	Controller Controller `json:"controller"`
+	Schedule   Schedule   `json:"schedule"`
 }
@@ -0,0 +1,29 @@
+// db/roles.go
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// RoleAdministrator is the name of the role allowed to perform destructive operations
+const RoleAdministrator = "Administrator"
+
+// HasRole reports whether a controller holds the named role at any facility
+func (s *Service) HasRole(ctx context.Context, controllerID int, roleName string) (bool, error) {
+	var exists bool
+
+	err := s.pool.QueryRow(ctx, `
+        SELECT EXISTS (
+            SELECT 1
+            FROM controller_facility_roles cfr
+            JOIN roles r ON r.id = cfr.role_id
+            WHERE cfr.controller_id = $1 AND r.name = $2
+        )
+    `, controllerID, roleName).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("error checking controller role: %w", err)
+	}
+
+	return exists, nil
+}
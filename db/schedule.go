@@ -3,9 +3,11 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/dukerupert/weekend-warrior/db/models"
+	"github.com/jackc/pgx/v5"
 )
 
 // CreateSchedule creates a new schedule in the database
@@ -14,13 +16,14 @@ func (s *Service) CreateSchedule(ctx context.Context, params models.CreateSchedu
 	err := s.pool.QueryRow(ctx, `
         INSERT INTO schedules (rdos, anchor, controller_id)
         VALUES ($1, $2, $3)
-        RETURNING id, created_at, rdos, anchor, controller_id
+        RETURNING id, created_at, rdos, anchor, controller_id, version
     `, params.RDOs, params.Anchor, params.ControllerID).Scan(
 		&schedule.ID,
 		&schedule.CreatedAt,
 		&schedule.RDOs,
 		&schedule.Anchor,
 		&schedule.ControllerID,
+		&schedule.Version,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("error creating schedule: %w", err)
@@ -32,7 +35,7 @@ func (s *Service) CreateSchedule(ctx context.Context, params models.CreateSchedu
 func (s *Service) GetSchedule(ctx context.Context, id int) (*models.Schedule, error) {
 	var schedule models.Schedule
 	err := s.pool.QueryRow(ctx, `
-        SELECT id, created_at, rdos, anchor, controller_id
+        SELECT id, created_at, rdos, anchor, controller_id, version
         FROM schedules
         WHERE id = $1
     `, id).Scan(
@@ -41,6 +44,7 @@ func (s *Service) GetSchedule(ctx context.Context, id int) (*models.Schedule, er
 		&schedule.RDOs,
 		&schedule.Anchor,
 		&schedule.ControllerID,
+		&schedule.Version,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("error getting schedule: %w", err)
@@ -52,7 +56,7 @@ func (s *Service) GetSchedule(ctx context.Context, id int) (*models.Schedule, er
 func (s *Service) GetScheduleByController(ctx context.Context, controllerID int) (*models.Schedule, error) {
 	var schedule models.Schedule
 	err := s.pool.QueryRow(ctx, `
-        SELECT id, created_at, rdos, anchor, controller_id
+        SELECT id, created_at, rdos, anchor, controller_id, version
         FROM schedules
         WHERE controller_id = $1
     `, controllerID).Scan(
@@ -61,6 +65,7 @@ func (s *Service) GetScheduleByController(ctx context.Context, controllerID int)
 		&schedule.RDOs,
 		&schedule.Anchor,
 		&schedule.ControllerID,
+		&schedule.Version,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("error getting schedule by controller: %w", err)
@@ -68,38 +73,127 @@ func (s *Service) GetScheduleByController(ctx context.Context, controllerID int)
 	return &schedule, nil
 }
 
-// UpdateSchedule updates an existing schedule in the database
-func (s *Service) UpdateSchedule(ctx context.Context, id int, params models.UpdateScheduleParams) (*models.Schedule, error) {
+// UpdateSchedule updates an existing schedule in the database if it is still
+// at the given version. ErrStaleVersion is returned when another update got
+// there first.
+func (s *Service) UpdateSchedule(ctx context.Context, id int, version int, params models.UpdateScheduleParams) (*models.Schedule, error) {
 	var schedule models.Schedule
 	err := s.pool.QueryRow(ctx, `
         UPDATE schedules
-        SET rdos = $1, anchor = $2
-        WHERE id = $3
-        RETURNING id, created_at, rdos, anchor, controller_id
-    `, params.RDOs, params.Anchor, id).Scan(
+        SET rdos = $1, anchor = $2, version = version + 1
+        WHERE id = $3 AND version = $4
+        RETURNING id, created_at, rdos, anchor, controller_id, version
+    `, params.RDOs, params.Anchor, id, version).Scan(
 		&schedule.ID,
 		&schedule.CreatedAt,
 		&schedule.RDOs,
 		&schedule.Anchor,
 		&schedule.ControllerID,
+		&schedule.Version,
 	)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, s.staleOrMissing(ctx, "schedules", id)
+		}
 		return nil, fmt.Errorf("error updating schedule: %w", err)
 	}
 	return &schedule, nil
 }
 
-// DeleteSchedule deletes a schedule from the database
-func (s *Service) DeleteSchedule(ctx context.Context, id int) error {
-	commandTag, err := s.pool.Exec(ctx, `
+// DeleteSchedule deletes a schedule from the database and returns the deleted row
+func (s *Service) DeleteSchedule(ctx context.Context, id int) (*models.Schedule, error) {
+	var schedule models.Schedule
+	err := s.pool.QueryRow(ctx, `
         DELETE FROM schedules
         WHERE id = $1
-    `, id)
+        RETURNING id, created_at, rdos, anchor, controller_id, version
+    `, id).Scan(
+		&schedule.ID,
+		&schedule.CreatedAt,
+		&schedule.RDOs,
+		&schedule.Anchor,
+		&schedule.ControllerID,
+		&schedule.Version,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("schedule with ID %d not found", id)
+		}
+		return nil, fmt.Errorf("error deleting schedule: %w", err)
+	}
+	return &schedule, nil
+}
+
+// GetFacilitySchedules retrieves every active controller at a facility that
+// has a schedule, together with that schedule, in a single query
+func (s *Service) GetFacilitySchedules(ctx context.Context, facilityID int) ([]models.ControllerSchedule, error) {
+	rows, err := s.pool.Query(ctx, `
+        SELECT c.id, c.created_at, c.name, c.initials, c.email, c.facility_id, c.archived_at, c.version,
+               sch.id, sch.created_at, sch.rdos, sch.anchor, sch.controller_id, sch.version
+        FROM controllers c
+        JOIN schedules sch ON sch.controller_id = c.id
+        WHERE c.facility_id = $1 AND c.archived_at IS NULL
+        ORDER BY c.name ASC
+    `, facilityID)
 	if err != nil {
-		return fmt.Errorf("error deleting schedule: %w", err)
+		return nil, fmt.Errorf("error listing facility schedules: %w", err)
 	}
-	if commandTag.RowsAffected() == 0 {
-		return fmt.Errorf("schedule not found")
+
+	return collectControllerSchedules(rows)
+}
+
+// GetControllerSchedules retrieves the given controllers that are active and
+// have a schedule, together with that schedule, ordered by name. Controllers
+// that are archived or have no schedule are left out.
+func (s *Service) GetControllerSchedules(ctx context.Context, controllerIDs []int) ([]models.ControllerSchedule, error) {
+	rows, err := s.pool.Query(ctx, `
+        SELECT c.id, c.created_at, c.name, c.initials, c.email, c.facility_id, c.archived_at, c.version,
+               sch.id, sch.created_at, sch.rdos, sch.anchor, sch.controller_id, sch.version
+        FROM controllers c
+        JOIN schedules sch ON sch.controller_id = c.id
+        WHERE c.id = ANY($1) AND c.archived_at IS NULL
+        ORDER BY c.name ASC
+    `, controllerIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error listing controller schedules: %w", err)
 	}
-	return nil
+
+	return collectControllerSchedules(rows)
+}
+
+// collectControllerSchedules scans rows of controller and schedule columns
+// and closes them
+func collectControllerSchedules(rows pgx.Rows) ([]models.ControllerSchedule, error) {
+	defer rows.Close()
+
+	var schedules []models.ControllerSchedule
+	for rows.Next() {
+		var cs models.ControllerSchedule
+		err := rows.Scan(
+			&cs.Controller.ID,
+			&cs.Controller.CreatedAt,
+			&cs.Controller.Name,
+			&cs.Controller.Initials,
+			&cs.Controller.Email,
+			&cs.Controller.FacilityID,
+			&cs.Controller.ArchivedAt,
+			&cs.Controller.Version,
+			&cs.Schedule.ID,
+			&cs.Schedule.CreatedAt,
+			&cs.Schedule.RDOs,
+			&cs.Schedule.Anchor,
+			&cs.Schedule.ControllerID,
+			&cs.Schedule.Version,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning controller schedule row: %w", err)
+		}
+		schedules = append(schedules, cs)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating controller schedule rows: %w", err)
+	}
+
+	return schedules, nil
 }
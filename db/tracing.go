@@ -0,0 +1,82 @@
+// db/tracing.go
+package db
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// queryTracer opens an OpenTelemetry span around every query, as a child of
+// whatever span the query's context carries
+type queryTracer struct {
+	tracer trace.Tracer
+}
+
+func newQueryTracer() queryTracer {
+	return queryTracer{tracer: otel.Tracer("github.com/dukerupert/weekend-warrior/db")}
+}
+
+// TraceQueryStart implements pgx.QueryTracer
+func (qt queryTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	sql := strings.Join(strings.Fields(data.SQL), " ")
+
+	attrs := []attribute.KeyValue{
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.query.text", sql),
+	}
+	if conn != nil {
+		attrs = append(attrs, attribute.String("db.namespace", conn.Config().Database))
+	}
+
+	ctx, _ = qt.tracer.Start(ctx, spanName(sql),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	return ctx
+}
+
+// TraceQueryEnd implements pgx.QueryTracer
+func (qt queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	if data.Err != nil {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+	} else {
+		span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+	}
+	span.End()
+}
+
+// spanName names a query span after its leading keyword, e.g. "db SELECT"
+func spanName(sql string) string {
+	keyword, _, _ := strings.Cut(sql, " ")
+	if keyword == "" {
+		return "db query"
+	}
+	return "db " + strings.ToUpper(keyword)
+}
+
+// tracers fans pgx trace callbacks out to several tracers in order, so the
+// query logger and the span tracer can both see every query
+type tracers []pgx.QueryTracer
+
+// TraceQueryStart implements pgx.QueryTracer
+func (ts tracers) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	for _, t := range ts {
+		ctx = t.TraceQueryStart(ctx, conn, data)
+	}
+	return ctx
+}
+
+// TraceQueryEnd implements pgx.QueryTracer
+func (ts tracers) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	for i := len(ts) - 1; i >= 0; i-- {
+		ts[i].TraceQueryEnd(ctx, conn, data)
+	}
+}
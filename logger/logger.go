@@ -3,6 +3,7 @@ package logger
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"time"
 
@@ -10,26 +11,85 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-// Setup initializes the logger with the given environment
-func Setup(environment string) {
+// Output formats accepted in Config.Format
+const (
+	FormatConsole = "console"
+	FormatJSON    = "json"
+)
+
+// Config controls where and how the application logs
+type Config struct {
+	Environment string
+	// Level overrides the level derived from Environment, e.g. "debug"
+	Level string
+	// Format is console or json. When empty, production logs JSON and
+	// every other environment uses the console format.
+	Format string
+	// File, when set, also receives every event as JSON
+	File string
+}
+
+// Setup initializes the global logger. The returned closer releases the log
+// file, if one was opened.
+func Setup(cfg Config) (io.Closer, error) {
+	level := getLogLevel(cfg.Environment)
+	if cfg.Level != "" {
+		parsed, err := zerolog.ParseLevel(cfg.Level)
+		if err != nil {
+			return nil, fmt.Errorf("invalid log level %q: %w", cfg.Level, err)
+		}
+		level = parsed
+	}
+
+	format := cfg.Format
+	if format == "" {
+		format = FormatConsole
+		if cfg.Environment == "production" {
+			format = FormatJSON
+		}
+	}
+
 	// Set global logger
 	zerolog.TimeFieldFormat = time.RFC3339
-	zerolog.SetGlobalLevel(getLogLevel(environment))
-
-	// Create multi writer for console output
-	output := zerolog.ConsoleWriter{
-		Out:        os.Stdout,
-		TimeFormat: time.RFC3339,
-		FormatLevel: func(i interface{}) string {
-			return fmt.Sprintf("| %-6s|", i)
-		},
+	zerolog.SetGlobalLevel(level)
+
+	var output io.Writer
+	switch format {
+	case FormatConsole:
+		output = zerolog.ConsoleWriter{
+			Out:        os.Stdout,
+			TimeFormat: time.RFC3339,
+			FormatLevel: func(i interface{}) string {
+				return fmt.Sprintf("| %-6s|", i)
+			},
+		}
+	case FormatJSON:
+		output = os.Stdout
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+
+	var closer io.Closer = nopCloser{}
+	if cfg.File != "" {
+		file, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+		if err != nil {
+			return nil, fmt.Errorf("error opening log file: %w", err)
+		}
+		output = zerolog.MultiLevelWriter(output, file)
+		closer = file
 	}
 
-	log.Logger = zerolog.New(output).
+	// Scrub personal data before any sink sees it
+	log.Logger = zerolog.New(newRedactor(output)).
 		With().
 		Timestamp().
-		Str("environment", environment).
+		Str("environment", cfg.Environment).
 		Logger()
+
+	// Contexts without a request-scoped logger fall back to the global one
+	zerolog.DefaultContextLogger = &log.Logger
+
+	return closer, nil
 }
 
 // getLogLevel returns the appropriate log level based on environment
@@ -43,3 +103,7 @@ func getLogLevel(environment string) zerolog.Level {
 		return zerolog.InfoLevel
 	}
 }
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,48 @@
+// logger/redact.go
+package logger
+
+import (
+	"io"
+	"regexp"
+)
+
+var (
+	// emailPattern matches addresses anywhere in an event, including inside
+	// error messages such as duplicate key details
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+	// bodyField matches a JSON "body" field and its string value, which
+	// holds a raw request body
+	bodyField = regexp.MustCompile(`"body":"(?:[^"\\]|\\.)*"`)
+)
+
+// Replacements written in place of redacted data
+const (
+	redactedEmail = "[redacted email]"
+	redactedBody  = `"body":"[redacted]"`
+)
+
+// redactor removes personal data from serialized zerolog events before
+// passing them on. zerolog hands each event to Write as one JSON object.
+// It is a backstop for data that slips into error messages; log calls
+// should name records by ID rather than rely on it.
+type redactor struct {
+	out io.Writer
+}
+
+func newRedactor(out io.Writer) redactor {
+	return redactor{out: out}
+}
+
+// Write implements io.Writer
+func (r redactor) Write(p []byte) (int, error) {
+	redacted := bodyField.ReplaceAll(p, []byte(redactedBody))
+	redacted = emailPattern.ReplaceAll(redacted, []byte(redactedEmail))
+
+	if _, err := r.out.Write(redacted); err != nil {
+		return 0, err
+	}
+
+	// Report the caller's length, the event was consumed in full
+	return len(p), nil
+}
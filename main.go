@@ -2,7 +2,11 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/dukerupert/weekend-warrior/pkg/app"
 	"github.com/dukerupert/weekend-warrior/pkg/config"
@@ -20,18 +24,35 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to create application: %v", err)
 	}
-	defer app.Cleanup()
 
 	// Setup routes and middleware
 	app.Setup()
 
+	// Stop on SIGINT (Ctrl+C) or SIGTERM (container stop)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Start the server
 	log.Printf("Starting server on port %s in %s mode",
 		app.Config.Server.Port,
 		app.Config.Server.Environment,
 	)
 
-	if err := app.Start(); err != nil {
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- app.Start()
+	}()
+
+	select {
+	case err := <-serverErr:
+		app.Cleanup()
 		log.Fatalf("Error starting server: %v", err)
+	case <-ctx.Done():
+		stop()
+	}
+
+	if err := app.Shutdown(); err != nil {
+		log.Printf("Error during shutdown: %v", err)
+		os.Exit(1)
 	}
 }
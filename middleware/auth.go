@@ -0,0 +1,104 @@
+// middleware/auth.go
+package middleware
+
+import (
+	"strings"
+
+	"github.com/dukerupert/weekend-warrior/db"
+	"github.com/dukerupert/weekend-warrior/db/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// userKey is the Locals key holding the authenticated controller
+const userKey = "user"
+
+// Auth returns a middleware that resolves a Supabase bearer token to a
+// controller. Requests without a token continue anonymously; requests with
+// an invalid token are rejected.
+func Auth(jwtSecret string, dbService *db.Service) fiber.Handler {
+	if jwtSecret == "" {
+		log.Warn().Msg("SUPABASE_JWT_SECRET not set, all requests will be anonymous")
+	}
+
+	return func(c *fiber.Ctx) error {
+		header := c.Get(fiber.HeaderAuthorization)
+		if jwtSecret == "" || !strings.HasPrefix(header, "Bearer ") {
+			return c.Next()
+		}
+
+		token, err := jwt.Parse(strings.TrimPrefix(header, "Bearer "), func(t *jwt.Token) (interface{}, error) {
+			return []byte(jwtSecret), nil
+		}, jwt.WithValidMethods([]string{"HS256"}))
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error":  "Invalid token",
+				"detail": err.Error(),
+			})
+		}
+
+		claims, ok := token.Claims.(jwt.MapClaims)
+		email, _ := claims["email"].(string)
+		if !ok || email == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error":  "Invalid token",
+				"detail": "token has no email claim",
+			})
+		}
+
+		controller, err := dbService.GetControllerByEmail(c.UserContext(), email)
+		if err != nil {
+			zerolog.Ctx(c.UserContext()).Warn().
+				Err(err).
+				Str("path", c.Path()).
+				Msg("token does not match an active controller")
+
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error":  "Unknown user",
+				"detail": "token does not match an active controller",
+			})
+		}
+
+		c.Locals(userKey, controller)
+		return c.Next()
+	}
+}
+
+// CurrentUser returns the authenticated controller, or nil for anonymous requests
+func CurrentUser(c *fiber.Ctx) *models.Controller {
+	controller, _ := c.Locals(userKey).(*models.Controller)
+	return controller
+}
+
+// RequireAdmin returns a middleware that only allows controllers holding the
+// Administrator role
+func RequireAdmin(dbService *db.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := CurrentUser(c)
+		if user == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error":  "Authentication required",
+				"detail": "this operation requires an administrator",
+			})
+		}
+
+		isAdmin, err := dbService.HasRole(c.UserContext(), user.ID, db.RoleAdministrator)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":  "Failed to check permissions",
+				"detail": err.Error(),
+			})
+		}
+
+		if !isAdmin {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":  "Forbidden",
+				"detail": "this operation requires an administrator",
+			})
+		}
+
+		return c.Next()
+	}
+}
@@ -4,37 +4,42 @@ package middleware
 import (
 	"time"
 
+	"github.com/dukerupert/weekend-warrior/pkg/metrics"
 	"github.com/gofiber/fiber/v2"
 	"github.com/rs/zerolog/log"
 )
 
-// Logger returns a middleware that logs HTTP requests
-func Logger() fiber.Handler {
+// Logger returns a middleware that logs HTTP requests. Requests to any of
+// skipPaths are served without being logged.
+func Logger(skipPaths ...string) fiber.Handler {
+	skip := make(map[string]bool, len(skipPaths))
+	for _, path := range skipPaths {
+		skip[path] = true
+	}
+
 	return func(c *fiber.Ctx) error {
+		if skip[c.Path()] {
+			return c.Next()
+		}
+
 		start := time.Now()
 
-		// Store request ID if present
-		requestID := c.Get("X-Request-ID")
-		if requestID == "" {
-			requestID = c.GetRespHeader("X-Request-ID")
-		}
+		// Request ID assigned by the RequestID middleware
+		requestID := GetRequestID(c)
 
-		// Handle panic recovery
-		defer func() {
-			if err := recover(); err != nil {
-				log.Error().
-					Str("request_id", requestID).
-					Str("method", c.Method()).
-					Str("path", c.Path()).
-					Int("status", fiber.StatusInternalServerError).
-					Dur("latency", time.Since(start)).
-					Interface("error", err).
-					Msg("panic recovered")
+		// Process request, rendering any error now so the logged status is
+		// the one the client receives
+		err := c.Next()
+		if err != nil {
+			if handlerErr := c.App().Config().ErrorHandler(c, err); handlerErr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
 			}
-		}()
+		}
 
-		// Process request
-		err := c.Next()
+		latency := time.Since(start)
+		status := c.Response().StatusCode()
+
+		metrics.ObserveRequest(c.Method(), routeLabel(c), status, latency)
 
 		// Build log entry
 		logEvent := log.Info()
@@ -48,11 +53,23 @@ func Logger() fiber.Handler {
 			Str("method", c.Method()).
 			Str("path", c.Path()).
 			Str("ip", c.IP()).
-			Int("status", c.Response().StatusCode()).
+			Int("status", status).
 			Str("user_agent", c.Get("User-Agent")).
-			Dur("latency", time.Since(start)).
+			Dur("latency", latency).
 			Msg("request processed")
 
-		return err
+		return nil
+	}
+}
+
+// routeLabel returns the matched route template, so /controllers/1 and
+// /controllers/2 share a label. Unknown paths, and requests answered by
+// middleware before routing, only reach the root middleware route; they are
+// grouped rather than leaking raw paths into labels.
+func routeLabel(c *fiber.Ctx) string {
+	route := c.Route().Path
+	if route == "/" && c.Path() != "/" {
+		return "unrouted"
 	}
+	return route
 }
@@ -0,0 +1,35 @@
+// middleware/methodoverride.go
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// MethodOverrideField is the form field naming the method a plain HTML form
+// stands in for, since browsers only submit forms as GET or POST
+const MethodOverrideField = "_method"
+
+// MethodOverride returns a middleware that routes a form POST as the PUT,
+// PATCH or DELETE named in its _method field, so page forms work without
+// htmx. Paths under apiPrefix are left alone; the API uses real methods.
+// It must be registered before any other middleware, because the request
+// is routed again from the start.
+func MethodOverride(apiPrefix string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Method() != fiber.MethodPost ||
+			strings.HasPrefix(c.Path(), apiPrefix) ||
+			!strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEApplicationForm) {
+			return c.Next()
+		}
+
+		switch method := strings.ToUpper(c.FormValue(MethodOverrideField)); method {
+		case fiber.MethodPut, fiber.MethodPatch, fiber.MethodDelete:
+			c.Method(method)
+			return c.RestartRouting()
+		}
+
+		return c.Next()
+	}
+}
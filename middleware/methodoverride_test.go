@@ -0,0 +1,70 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dukerupert/weekend-warrior/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestMethodOverride(t *testing.T) {
+	app := fiber.New()
+	app.Use(middleware.MethodOverride("/api/v1"))
+
+	// Counts how often the rest of the stack runs for a request
+	var passes int
+	app.Use(func(c *fiber.Ctx) error {
+		passes++
+		return c.Next()
+	})
+
+	answer := func(c *fiber.Ctx) error { return c.SendString(c.Method()) }
+	for _, path := range []string{"/things/1", "/api/v1/things/1"} {
+		app.Post(path, answer)
+		app.Put(path, answer)
+		app.Delete(path, answer)
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{"delete", "/things/1", fiber.MIMEApplicationForm, "_method=DELETE", fiber.MethodDelete},
+		{"put", "/things/1", fiber.MIMEApplicationForm, "name=x&_method=put", fiber.MethodPut},
+		{"no override", "/things/1", fiber.MIMEApplicationForm, "name=x", fiber.MethodPost},
+		{"unsupported method", "/things/1", fiber.MIMEApplicationForm, "_method=GET", fiber.MethodPost},
+		{"not a form", "/things/1", fiber.MIMEApplicationJSON, `{"_method":"DELETE"}`, fiber.MethodPost},
+		{"api", "/api/v1/things/1", fiber.MIMEApplicationForm, "_method=DELETE", fiber.MethodPost},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			passes = 0
+
+			req := httptest.NewRequest(fiber.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set(fiber.HeaderContentType, tt.contentType)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(body) != tt.want {
+				t.Errorf("routed as %q, want %q", body, tt.want)
+			}
+			if passes != 1 {
+				t.Errorf("middleware ran %d times, want once", passes)
+			}
+		})
+	}
+}
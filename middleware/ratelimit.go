@@ -0,0 +1,130 @@
+// middleware/ratelimit.go
+package middleware
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dukerupert/weekend-warrior/pkg/ratelimit"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+)
+
+// Rate limit response headers, following the IETF RateLimit header fields draft
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+)
+
+// RateLimitConfig sets the request budget per window. A limit of zero
+// disables that tier.
+type RateLimitConfig struct {
+	Window time.Duration
+	// Anonymous is the budget per client IP for unauthenticated requests
+	Anonymous int
+	// Authenticated is the budget per controller
+	Authenticated int
+	// Mutating is a separate, stricter budget for non-safe methods under
+	// MutatingPrefix, counted per controller or client IP
+	Mutating       int
+	MutatingPrefix string
+}
+
+// rateLimitTier is one budget a request is counted against
+type rateLimitTier struct {
+	key   string
+	limit int
+}
+
+// RateLimit returns a middleware that counts requests per authenticated
+// controller, or per client IP for anonymous requests, and answers 429 once
+// a budget is spent. It must run after Auth. If the store fails the request
+// is let through.
+func RateLimit(cfg RateLimitConfig, store ratelimit.Store) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		tiers := rateLimitTiers(c, cfg)
+		if len(tiers) == 0 {
+			return c.Next()
+		}
+
+		// Count against every tier, reporting the one closest to running out
+		var (
+			limit     int
+			remaining = math.MaxInt
+			reset     time.Duration
+			exceeded  bool
+		)
+		for _, tier := range tiers {
+			count, tierReset, err := store.Hit(c.UserContext(), tier.key, cfg.Window)
+			if err != nil {
+				zerolog.Ctx(c.UserContext()).Warn().
+					Err(err).
+					Str("key", tier.key).
+					Msg("rate limit store failed, allowing request")
+				return c.Next()
+			}
+
+			tierRemaining := max(tier.limit-count, 0)
+			if count > tier.limit {
+				limit, remaining, reset, exceeded = tier.limit, 0, tierReset, true
+				break
+			}
+			if tierRemaining < remaining {
+				limit, remaining, reset = tier.limit, tierRemaining, tierReset
+			}
+		}
+
+		resetSeconds := strconv.Itoa(int(math.Ceil(reset.Seconds())))
+		c.Set(HeaderRateLimitLimit, strconv.Itoa(limit))
+		c.Set(HeaderRateLimitRemaining, strconv.Itoa(remaining))
+		c.Set(HeaderRateLimitReset, resetSeconds)
+
+		if exceeded {
+			zerolog.Ctx(c.UserContext()).Info().
+				Str("path", c.Path()).
+				Int("limit", limit).
+				Msg("rate limit exceeded")
+
+			c.Set(fiber.HeaderRetryAfter, resetSeconds)
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error":  "Too many requests",
+				"detail": "rate limit exceeded, retry in " + resetSeconds + " seconds",
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// rateLimitTiers picks the budgets that apply to a request
+func rateLimitTiers(c *fiber.Ctx, cfg RateLimitConfig) []rateLimitTier {
+	identity := "ip:" + c.IP()
+	limit := cfg.Anonymous
+	if user := CurrentUser(c); user != nil {
+		identity = "user:" + strconv.Itoa(user.ID)
+		limit = cfg.Authenticated
+	}
+
+	var tiers []rateLimitTier
+	if cfg.Mutating > 0 && isMutating(c.Method()) && strings.HasPrefix(c.Path(), cfg.MutatingPrefix) {
+		tiers = append(tiers, rateLimitTier{key: "mutating:" + identity, limit: cfg.Mutating})
+	}
+	if limit > 0 {
+		tiers = append(tiers, rateLimitTier{key: "all:" + identity, limit: limit})
+	}
+
+	return tiers
+}
+
+// isMutating reports whether method may change server state
+func isMutating(method string) bool {
+	switch method {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return false
+	default:
+		return true
+	}
+}
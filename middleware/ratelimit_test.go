@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dukerupert/weekend-warrior/db/models"
+	"github.com/dukerupert/weekend-warrior/pkg/ratelimit"
+	"github.com/gofiber/fiber/v2"
+)
+
+// testUserHeader stands in for Auth in these tests, naming the controller
+// ID a request is authenticated as
+const testUserHeader = "X-Test-User"
+
+// rateLimitedApp serves GET and POST / behind RateLimit, trusting
+// X-Forwarded-For for the client IP
+func rateLimitedApp(cfg RateLimitConfig, store ratelimit.Store) *fiber.App {
+	app := fiber.New(fiber.Config{
+		ProxyHeader:        fiber.HeaderXForwardedFor,
+		EnableIPValidation: true,
+	})
+	app.Use(func(c *fiber.Ctx) error {
+		if id, err := strconv.Atoi(c.Get(testUserHeader)); err == nil {
+			c.Locals(userKey, &models.Controller{ID: id})
+		}
+		return c.Next()
+	})
+	app.Use(RateLimit(cfg, store))
+
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+	app.Get("/", ok)
+	app.Post("/", ok)
+	return app
+}
+
+// hit sends one request from ip, authenticated as user unless it is zero
+func hit(t *testing.T, app *fiber.App, method, ip string, user int) *http.Response {
+	t.Helper()
+
+	req := httptest.NewRequest(method, "/", nil)
+	req.Header.Set(fiber.HeaderXForwardedFor, ip)
+	if user != 0 {
+		req.Header.Set(testUserHeader, strconv.Itoa(user))
+	}
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestRateLimitHeadersAndStatus(t *testing.T) {
+	app := rateLimitedApp(RateLimitConfig{Window: time.Minute, Anonymous: 2}, ratelimit.NewMemoryStore())
+
+	tests := []struct {
+		status    int
+		remaining string
+	}{
+		{fiber.StatusOK, "1"},
+		{fiber.StatusOK, "0"},
+		{fiber.StatusTooManyRequests, "0"},
+	}
+	for i, tt := range tests {
+		resp := hit(t, app, fiber.MethodGet, "192.0.2.1", 0)
+
+		if resp.StatusCode != tt.status {
+			t.Errorf("request %d: status = %d, want %d", i+1, resp.StatusCode, tt.status)
+		}
+		if got := resp.Header.Get(HeaderRateLimitLimit); got != "2" {
+			t.Errorf("request %d: %s = %q, want 2", i+1, HeaderRateLimitLimit, got)
+		}
+		if got := resp.Header.Get(HeaderRateLimitRemaining); got != tt.remaining {
+			t.Errorf("request %d: %s = %q, want %s", i+1, HeaderRateLimitRemaining, got, tt.remaining)
+		}
+		if got := resp.Header.Get(HeaderRateLimitReset); got != "60" {
+			t.Errorf("request %d: %s = %q, want 60", i+1, HeaderRateLimitReset, got)
+		}
+
+		retryAfter := resp.Header.Get(fiber.HeaderRetryAfter)
+		if tt.status == fiber.StatusTooManyRequests && retryAfter != "60" {
+			t.Errorf("request %d: Retry-After = %q, want 60", i+1, retryAfter)
+		}
+		if tt.status == fiber.StatusOK && retryAfter != "" {
+			t.Errorf("request %d: Retry-After = %q on an allowed request", i+1, retryAfter)
+		}
+	}
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	cfg := RateLimitConfig{Window: time.Minute, Anonymous: 1, Authenticated: 1}
+
+	// Each case starts from a fresh store, spends a budget in setup and
+	// then checks the next request
+	tests := []struct {
+		name   string
+		setup  func(t *testing.T, app *fiber.App)
+		ip     string
+		user   int
+		status int
+	}{
+		{
+			name:   "anonymous budget is per IP",
+			setup:  func(t *testing.T, app *fiber.App) { hit(t, app, fiber.MethodGet, "192.0.2.1", 0) },
+			ip:     "192.0.2.2",
+			status: fiber.StatusOK,
+		},
+		{
+			name:   "anonymous IP runs out",
+			setup:  func(t *testing.T, app *fiber.App) { hit(t, app, fiber.MethodGet, "192.0.2.1", 0) },
+			ip:     "192.0.2.1",
+			status: fiber.StatusTooManyRequests,
+		},
+		{
+			name:   "signing in on a spent IP uses the controller's budget",
+			setup:  func(t *testing.T, app *fiber.App) { hit(t, app, fiber.MethodGet, "192.0.2.1", 0) },
+			ip:     "192.0.2.1",
+			user:   7,
+			status: fiber.StatusOK,
+		},
+		{
+			name:   "a controller's budget follows them across IPs",
+			setup:  func(t *testing.T, app *fiber.App) { hit(t, app, fiber.MethodGet, "192.0.2.1", 7) },
+			ip:     "192.0.2.2",
+			user:   7,
+			status: fiber.StatusTooManyRequests,
+		},
+		{
+			name:   "controllers sharing an IP have their own budgets",
+			setup:  func(t *testing.T, app *fiber.App) { hit(t, app, fiber.MethodGet, "192.0.2.1", 7) },
+			ip:     "192.0.2.1",
+			user:   8,
+			status: fiber.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := rateLimitedApp(cfg, ratelimit.NewMemoryStore())
+			tt.setup(t, app)
+
+			if resp := hit(t, app, fiber.MethodGet, tt.ip, tt.user); resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
+
+func TestRateLimitMutatingBudget(t *testing.T) {
+	cfg := RateLimitConfig{Window: time.Minute, Anonymous: 10, Mutating: 1, MutatingPrefix: "/"}
+	app := rateLimitedApp(cfg, ratelimit.NewMemoryStore())
+
+	if resp := hit(t, app, fiber.MethodPost, "192.0.2.1", 0); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("first POST: status = %d, want 200", resp.StatusCode)
+	}
+
+	resp := hit(t, app, fiber.MethodPost, "192.0.2.1", 0)
+	if resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Errorf("second POST: status = %d, want 429", resp.StatusCode)
+	}
+	if got := resp.Header.Get(HeaderRateLimitLimit); got != "1" {
+		t.Errorf("second POST: %s = %q, want the mutating limit", HeaderRateLimitLimit, got)
+	}
+
+	if resp := hit(t, app, fiber.MethodGet, "192.0.2.1", 0); resp.StatusCode != fiber.StatusOK {
+		t.Errorf("GET after the mutating budget ran out: status = %d, want 200", resp.StatusCode)
+	}
+}
+
+// failingStore always fails, as an unreachable Redis would
+type failingStore struct{}
+
+func (failingStore) Hit(context.Context, string, time.Duration) (int, time.Duration, error) {
+	return 0, 0, errors.New("store unavailable")
+}
+
+func TestRateLimitAllowsWhenStoreFails(t *testing.T) {
+	app := rateLimitedApp(RateLimitConfig{Window: time.Minute, Anonymous: 1}, failingStore{})
+
+	for i := 0; i < 3; i++ {
+		if resp := hit(t, app, fiber.MethodGet, "192.0.2.1", 0); resp.StatusCode != fiber.StatusOK {
+			t.Errorf("request %d: status = %d, want 200", i+1, resp.StatusCode)
+		}
+	}
+}
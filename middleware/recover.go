@@ -0,0 +1,43 @@
+// middleware/recover.go
+package middleware
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+)
+
+// panics counts recovered handler panics since the process started
+var panics atomic.Uint64
+
+// Recover returns a middleware that turns a handler panic into a 500 error,
+// which the app's error handler then renders. The panic value and stack trace
+// are logged through the request's logger, which carries its request ID.
+func Recover() fiber.Handler {
+	return func(c *fiber.Ctx) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				panics.Add(1)
+
+				zerolog.Ctx(c.UserContext()).Error().
+					Str("method", c.Method()).
+					Str("path", c.Path()).
+					Str("panic", fmt.Sprint(r)).
+					Str("stack", string(debug.Stack())).
+					Msg("panic recovered")
+
+				err = fiber.NewError(fiber.StatusInternalServerError, "Internal Server Error")
+			}
+		}()
+
+		return c.Next()
+	}
+}
+
+// PanicCount returns the number of handler panics recovered so far
+func PanicCount() uint64 {
+	return panics.Load()
+}
@@ -0,0 +1,119 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/dukerupert/weekend-warrior/middleware"
+	"github.com/dukerupert/weekend-warrior/website"
+	"github.com/dukerupert/weekend-warrior/website/handlers"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestMain(m *testing.M) {
+	// Recovered panics log their stack trace; keep test output readable
+	log.Logger = zerolog.New(io.Discard)
+	os.Exit(m.Run())
+}
+
+// panickingApp mounts a panicking handler behind RequestID and Recover, on
+// both an API path and a page path, with the app's error handler and views
+func panickingApp(t *testing.T) *fiber.App {
+	t.Helper()
+
+	site, err := website.New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New(fiber.Config{
+		ErrorHandler: handlers.ErrorHandler,
+		Views:        site.Engine(),
+		ViewsLayout:  handlers.Layout,
+	})
+	app.Use(middleware.RequestID())
+	app.Use(middleware.Recover())
+
+	boom := func(c *fiber.Ctx) error {
+		panic("boom")
+	}
+	app.Get("/api/v1/boom", boom)
+	app.Get("/boom", boom)
+
+	return app
+}
+
+func TestRecoverAPI(t *testing.T) {
+	app := panickingApp(t)
+	before := middleware.PanicCount()
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/boom", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != fiber.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", resp.StatusCode)
+	}
+	if resp.Header.Get(middleware.RequestIDHeader) == "" {
+		t.Error("response has no X-Request-ID")
+	}
+	if got := middleware.PanicCount(); got != before+1 {
+		t.Errorf("PanicCount() = %d, want %d", got, before+1)
+	}
+
+	var body struct {
+		Error  string `json:"error"`
+		Detail string `json:"detail"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("body is not the JSON error envelope: %v", err)
+	}
+	if body.Error == "" || !strings.Contains(body.Detail, resp.Header.Get(middleware.RequestIDHeader)) {
+		t.Errorf("envelope = %+v, want an error and the request ID in the detail", body)
+	}
+	if strings.Contains(body.Detail, "boom") {
+		t.Error("envelope exposes the panic value")
+	}
+}
+
+func TestRecoverPage(t *testing.T) {
+	app := panickingApp(t)
+	before := middleware.PanicCount()
+
+	req := httptest.NewRequest(fiber.MethodGet, "/boom", nil)
+	req.Header.Set(fiber.HeaderAccept, fiber.MIMETextHTML)
+	req.Header.Set(middleware.RequestIDHeader, "page-panic-1")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != fiber.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", resp.StatusCode)
+	}
+	if got := resp.Header.Get(middleware.RequestIDHeader); got != "page-panic-1" {
+		t.Errorf("X-Request-ID = %q, want the client's ID", got)
+	}
+	if got := middleware.PanicCount(); got != before+1 {
+		t.Errorf("PanicCount() = %d, want %d", got, before+1)
+	}
+
+	if ct := resp.Header.Get(fiber.HeaderContentType); !strings.HasPrefix(ct, fiber.MIMETextHTML) {
+		t.Errorf("Content-Type = %q, want an HTML error page", ct)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), "page-panic-1") {
+		t.Error("error page does not show the request ID")
+	}
+}
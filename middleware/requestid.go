@@ -0,0 +1,45 @@
+// middleware/requestid.go
+package middleware
+
+import (
+	"regexp"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// RequestIDHeader carries the request ID on requests and responses
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey is the Locals key holding the request ID
+const requestIDKey = "request_id"
+
+// validRequestID limits client-supplied IDs to something safe to log and echo
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// RequestID returns a middleware that reuses the client's X-Request-ID or
+// generates one, echoes it on the response, and stores a logger carrying it
+// in the request's user context for handlers and the database layer
+func RequestID() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestID := c.Get(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = utils.UUIDv4()
+		}
+
+		c.Set(RequestIDHeader, requestID)
+		c.Locals(requestIDKey, requestID)
+
+		reqLogger := log.With().Str("request_id", requestID).Logger()
+		c.SetUserContext(reqLogger.WithContext(c.UserContext()))
+
+		return c.Next()
+	}
+}
+
+// GetRequestID returns the ID assigned to the current request
+func GetRequestID(c *fiber.Ctx) string {
+	requestID, _ := c.Locals(requestIDKey).(string)
+	return requestID
+}
@@ -0,0 +1,98 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dukerupert/weekend-warrior/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+// csrfApp serves a page that prints its CSRF token, and a page and an API
+// route accepting POSTs
+func csrfApp() *fiber.App {
+	app := fiber.New()
+	app.Use(middleware.CSRF(false, nil, "/api/v1"))
+
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString(middleware.CSRFToken(c)) })
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+	app.Post("/things", ok)
+	app.Post("/api/v1/things", ok)
+	return app
+}
+
+// csrfSession loads the page and returns its CSRF cookie and the token the
+// page would embed in its forms
+func csrfSession(t *testing.T, app *fiber.App) (*http.Cookie, string) {
+	t.Helper()
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == middleware.CSRFCookieName {
+			if cookie.Value != string(token) {
+				t.Fatalf("CSRFToken() = %q, cookie holds %q", token, cookie.Value)
+			}
+			return cookie, string(token)
+		}
+	}
+	t.Fatal("page did not set the CSRF cookie")
+	return nil, ""
+}
+
+func TestCSRF(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		header bool
+		field  bool
+		cookie bool
+		want   int
+	}{
+		{"header", "/things", true, false, true, fiber.StatusOK},
+		{"form field", "/things", false, true, true, fiber.StatusOK},
+		{"no token", "/things", false, false, true, fiber.StatusForbidden},
+		{"token without the cookie", "/things", false, true, false, fiber.StatusForbidden},
+		{"api", "/api/v1/things", false, false, false, fiber.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := csrfApp()
+			cookie, token := csrfSession(t, app)
+
+			form := url.Values{"name": {"x"}}
+			if tt.field {
+				form.Set(middleware.CSRFFormField, token)
+			}
+
+			req := httptest.NewRequest(fiber.MethodPost, tt.path, strings.NewReader(form.Encode()))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationForm)
+			if tt.header {
+				req.Header.Set(middleware.CSRFHeader, token)
+			}
+			if tt.cookie {
+				req.AddCookie(cookie)
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
@@ -2,17 +2,28 @@
 package app
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"sync"
+	"time"
 
 	"github.com/dukerupert/weekend-warrior/db"
 	"github.com/dukerupert/weekend-warrior/logger"
 	"github.com/dukerupert/weekend-warrior/middleware"
 	"github.com/dukerupert/weekend-warrior/pkg/config"
+	"github.com/dukerupert/weekend-warrior/pkg/metrics"
+	"github.com/dukerupert/weekend-warrior/pkg/ratelimit"
+	"github.com/dukerupert/weekend-warrior/pkg/redisstorage"
+	"github.com/dukerupert/weekend-warrior/pkg/tracing"
 	"github.com/dukerupert/weekend-warrior/services/calendar"
+	"github.com/dukerupert/weekend-warrior/website"
 	"github.com/dukerupert/weekend-warrior/website/handlers"
+	"github.com/dukerupert/weekend-warrior/website/openapi"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/template/html/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
 )
 
@@ -22,12 +33,61 @@ type App struct {
 	Fiber    *fiber.App
 	Config   *config.Config
 	Calendar *calendar.Service
+	// Redis is nil unless REDIS_HOST is configured
+	Redis *redis.Client
+
+	// site holds the page templates and static files
+	site *website.Site
+
+	// logFile is the optional log file sink, closed last
+	logFile io.Closer
+
+	// stopTracing flushes buffered spans on shutdown
+	stopTracing func(context.Context) error
+
+	// Background workers run until shutdown cancels ctx
+	ctx     context.Context
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
 }
 
 // New creates a new instance of App with all dependencies
 func New(cfg *config.Config) (*App, error) {
 	// Initialize logger
-	logger.Setup(cfg.Server.Environment)
+	logFile, err := logger.Setup(logger.Config{
+		Environment: cfg.Server.Environment,
+		Level:       cfg.Log.Level,
+		Format:      cfg.Log.Format,
+		File:        cfg.Log.File,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize logger: %v", err)
+	}
+
+	// Install the tracer provider before anything creates spans
+	stopTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		ServiceName: cfg.Tracing.ServiceName,
+		Environment: cfg.Server.Environment,
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to initialize tracing")
+		return nil, fmt.Errorf("unable to initialize tracing: %v", err)
+	}
+
+	// Use the templates and static files built into the binary, or the
+	// ones on disk during development
+	site, err := website.New(cfg.Server.AssetsDir)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load website assets")
+		return nil, fmt.Errorf("unable to load website assets: %v", err)
+	}
+	if cfg.Server.AssetsDir != "" {
+		log.Info().
+			Str("dir", cfg.Server.AssetsDir).
+			Msg("loading views and static files from disk")
+	}
 
 	// Initialize DB service
 	dbService, err := db.NewService(db.Config{
@@ -38,59 +98,202 @@ func New(cfg *config.Config) (*App, error) {
 		return nil, fmt.Errorf("unable to initialize database service: %v", err)
 	}
 
+	// Connect to Redis when configured
+	var redisClient *redis.Client
+	if cfg.Redis.Enabled() {
+		redisClient = redis.NewClient(&redis.Options{
+			Addr:     cfg.Redis.Addr(),
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		})
+		if err := redisClient.Ping(context.Background()).Err(); err != nil {
+			dbService.Close()
+			log.Error().Err(err).Msg("failed to connect to redis")
+			return nil, fmt.Errorf("unable to connect to redis: %v", err)
+		}
+	}
+
 	// Create Fiber instance with config
 	fiberApp := fiber.New(fiber.Config{
 		ReadTimeout:       cfg.Server.ReadTimeout,
 		WriteTimeout:      cfg.Server.WriteTimeout,
-		Views:             html.New("./website/views", ".html"),
+		Views:             site.Engine(),
+		ViewsLayout:       handlers.Layout,
 		PassLocalsToViews: false,
+		ErrorHandler:      handlers.ErrorHandler,
+		ProxyHeader:       cfg.Server.ProxyHeader,
+		// Only trust proxy header values that parse as IPs
+		EnableIPValidation: true,
 	})
 
-	// Add logger middleware
-	fiberApp.Use(middleware.Logger())
+	// Route forms posted without htmx as the PUT or DELETE they stand in
+	// for. It restarts routing, so it comes before anything else.
+	fiberApp.Use(middleware.MethodOverride(openapi.Prefix))
+
+	// Assign request IDs before anything logs
+	fiberApp.Use(middleware.RequestID())
+
+	// Add logger middleware, leaving out the orchestrator probes and scrapes
+	fiberApp.Use(middleware.Logger(append(handlers.ProbePaths, metrics.Path)...))
 
-	// Initialize calendar service with the DB pool
-	calendarService := calendar.NewService(dbService.GetPool())
+	// Wrap each request in a span, inside the logger so the trace ID is
+	// available to everything downstream
+	fiberApp.Use(middleware.Tracing(append(handlers.ProbePaths, metrics.Path)...))
+
+	// Turn handler panics into 500 responses
+	fiberApp.Use(middleware.Recover())
+
+	// Send the baseline browser security headers on every response
+	fiberApp.Use(middleware.SecurityHeaders(cfg.Server.ContentSecurityPolicy, cfg.Server.HSTSMaxAge))
+
+	// Allow the configured origins to call the API, answering preflights
+	// before auth and rate limiting
+	if corsHandler := middleware.CORS(cfg.Server.AllowedOrigins); corsHandler != nil {
+		fiberApp.Use(corsHandler)
+	}
+
+	// Initialize calendar service, caching facility months in Redis when
+	// it is configured and in process otherwise
+	var calendarCache calendar.Cache = calendar.NewLRUCache(cfg.Calendar.CacheSize, cfg.Calendar.CacheTTL)
+	if redisClient != nil {
+		calendarCache = calendar.NewRedisCache(redisClient, cfg.Calendar.CacheTTL)
+	}
+	calendarService := calendar.NewService(dbService, calendarCache)
+
+	ctx, cancel := context.WithCancel(context.Background())
 
 	return &App{
-		DB:       dbService,
-		Fiber:    fiberApp,
-		Config:   cfg,
-		Calendar: calendarService,
+		DB:          dbService,
+		Fiber:       fiberApp,
+		Config:      cfg,
+		Calendar:    calendarService,
+		Redis:       redisClient,
+		site:        site,
+		logFile:     logFile,
+		stopTracing: stopTracing,
+		ctx:         ctx,
+		cancel:      cancel,
 	}, nil
 }
 
 // Setup configures our routes and middleware
 func (a *App) Setup() {
-	// Store DB pool in context for handlers to use
-	a.Fiber.Use(func(c *fiber.Ctx) error {
-		c.Locals("db", a.DB.GetPool())
-		return c.Next()
-	})
+	// Register metrics collectors and start the gauge refresher
+	a.setupMetrics()
 
 	// Create and register handlers
 	a.setupHandlers()
+
+	// The openapi tests keep the spec and routes in step; a mismatch here
+	// means a build skipped them, which is worth a warning but not an outage
+	if err := openapi.Verify(a.Fiber.GetRoutes(true)); err != nil {
+		log.Warn().Err(err).Msg("OpenAPI spec does not match registered routes")
+	}
+}
+
+// setupMetrics registers collectors that depend on the app's resources
+func (a *App) setupMetrics() {
+	metrics.RegisterPool(a.DB.GetPool())
+
+	metrics.Registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Name: "http_panics_recovered_total",
+		Help: "Handler panics recovered and answered with a 500.",
+	}, func() float64 {
+		return float64(middleware.PanicCount())
+	}))
+
+	a.Go("domain-metrics", func(ctx context.Context) {
+		metrics.RefreshDomain(ctx, a.DB, a.Config.Metrics.RefreshInterval)
+	})
+}
+
+// rateLimitStore shares counters through Redis when it is configured and
+// keeps them in memory otherwise
+func (a *App) rateLimitStore() ratelimit.Store {
+	if a.Redis != nil {
+		return ratelimit.NewRedisStore(a.Redis, "ratelimit:")
+	}
+	return ratelimit.NewMemoryStore()
+}
+
+// csrfStorage shares CSRF tokens through Redis when it is configured. It
+// returns a nil interface otherwise, which keeps tokens in memory.
+func (a *App) csrfStorage() fiber.Storage {
+	if a.Redis != nil {
+		return redisstorage.New(a.Redis, "csrf:")
+	}
+	return nil
 }
 
 // setupHandlers initializes and registers all handlers
 func (a *App) setupHandlers() {
+	// Probes and metrics come first so they never pass through auth or
+	// rate limiting; metrics check their own scrape token instead
+	healthHandler := handlers.NewHealthHandler(a.DB)
+	healthHandler.RegisterRoutes(a.Fiber)
+	if a.Config.Metrics.Token != "" {
+		a.Fiber.Get(metrics.Path, metrics.Handler(a.Config.Metrics.Token))
+	} else {
+		log.Warn().
+			Str("path", metrics.Path).
+			Msg("METRICS_TOKEN is not set, metrics are not served")
+	}
+
+	// Static files skip auth, rate limiting and CSRF as well
+	a.site.RegisterRoutes(a.Fiber)
+
+	// Store DB pool in context for handlers to use
+	a.Fiber.Use(func(c *fiber.Ctx) error {
+		c.Locals("db", a.DB.GetPool())
+		return c.Next()
+	})
+
+	// Resolve the authenticated controller, if any
+	a.Fiber.Use(middleware.Auth(a.Config.Supabase.Jwt_secret, a.DB))
+
+	// Limit request rates per controller or client IP. Page forms mutate
+	// outside the API prefix, so the mutating budget covers every path.
+	a.Fiber.Use(middleware.RateLimit(middleware.RateLimitConfig{
+		Window:         a.Config.RateLimit.Window,
+		Anonymous:      a.Config.RateLimit.Anonymous,
+		Authenticated:  a.Config.RateLimit.Authenticated,
+		Mutating:       a.Config.RateLimit.Mutating,
+		MutatingPrefix: "/",
+	}, a.rateLimitStore()))
+
+	// Require a CSRF token on mutations coming from our pages; the API
+	// authenticates with bearer tokens only and is exempt
+	a.Fiber.Use(middleware.CSRF(a.Config.Server.Environment == "production", a.csrfStorage(), openapi.Prefix))
+
+	// Give every page the signed-in controller and flash message for the
+	// layout
+	a.Fiber.Use(handlers.LayoutData())
+
 	// Create calendar handler
-	calendarHandler := handlers.NewCalendarHandler(a.Calendar)
+	calendarHandler := handlers.NewCalendarHandler(a.Calendar, a.DB)
 
 	// Initialize and register facility handler
 	facilityHandler := handlers.NewFacilityHandler(a.DB)
 	facilityHandler.RegisterRoutes(a.Fiber)
 
 	// Initialize and register controllers handler
-	controllersHandler := handlers.NewControllerHandler(a.DB)
+	controllersHandler := handlers.NewControllerHandler(a.DB, a.Calendar)
 	controllersHandler.RegisterRoutes(a.Fiber)
 
 	// Initialize and register schedule handlers
-	scheduleHandler := handlers.NewScheduleHandler(a.DB)
+	scheduleHandler := handlers.NewScheduleHandler(a.DB, a.Calendar)
 	scheduleHandler.RegisterRoutes(a.Fiber)
 
-	// Setup root route
+	// Serve the OpenAPI spec and docs UI
+	openapi.RegisterRoutes(a.Fiber)
+
+	// Browser pages live outside the API prefix and render HTML, including
+	// their errors
 	a.Fiber.Get("/", calendarHandler.CalendarHandler)
+	a.Fiber.Get("/year", calendarHandler.YearHandler)
+	facilityHandler.RegisterPageRoutes(a.Fiber)
+	controllersHandler.RegisterPageRoutes(a.Fiber)
+	scheduleHandler.RegisterPageRoutes(a.Fiber)
 }
 
 // Start begins listening for requests
@@ -98,9 +301,70 @@ func (a *App) Start() error {
 	return a.Fiber.Listen(":" + a.Config.Server.Port)
 }
 
-// Cleanup handles graceful shutdown
+// Go runs a background worker until the app shuts down. The worker must
+// return once ctx is cancelled.
+func (a *App) Go(name string, worker func(ctx context.Context)) {
+	a.workers.Add(1)
+	go func() {
+		defer a.workers.Done()
+		log.Debug().Str("worker", name).Msg("background worker started")
+		worker(a.ctx)
+		log.Debug().Str("worker", name).Msg("background worker stopped")
+	}()
+}
+
+// Shutdown stops accepting connections, waits for in-flight requests up to
+// the configured deadline, stops background workers and then closes the
+// database pool
+func (a *App) Shutdown() error {
+	deadline := time.Now().Add(a.Config.Server.ShutdownTimeout)
+
+	log.Info().
+		Dur("timeout", a.Config.Server.ShutdownTimeout).
+		Msg("shutting down, draining in-flight requests")
+
+	err := a.Fiber.ShutdownWithTimeout(a.Config.Server.ShutdownTimeout)
+	if err != nil {
+		log.Error().Err(err).Msg("in-flight requests did not finish before the deadline")
+	}
+
+	// Stop background workers, bounded by what is left of the deadline
+	a.cancel()
+	stopped := make(chan struct{})
+	go func() {
+		a.workers.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Until(deadline)):
+		log.Warn().Msg("background workers did not stop before the deadline")
+	}
+
+	// Flush spans still buffered in the exporter
+	flushCtx, cancelFlush := context.WithDeadline(context.Background(), deadline)
+	defer cancelFlush()
+	if err := a.stopTracing(flushCtx); err != nil {
+		log.Warn().Err(err).Msg("failed to flush traces")
+	}
+
+	// Log before Cleanup closes the log file
+	log.Info().Msg("shutdown complete")
+	a.Cleanup()
+
+	return err
+}
+
+// Cleanup releases resources held by the app
 func (a *App) Cleanup() {
 	if a.DB != nil {
 		a.DB.Close()
 	}
+	if a.Redis != nil {
+		a.Redis.Close()
+	}
+	if a.logFile != nil {
+		a.logFile.Close()
+	}
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -11,17 +12,34 @@ import (
 
 // Config holds all configuration for our application
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Supabase SupabaseConfig
-	Redis    RedisConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Supabase  SupabaseConfig
+	Redis     RedisConfig
+	Metrics   MetricsConfig
+	Tracing   TracingConfig
+	Log       LogConfig
+	RateLimit RateLimitConfig
+	Calendar  CalendarConfig
 }
 
 type ServerConfig struct {
-	Port         string
-	Environment  string
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
+	Port            string
+	Environment     string
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	ShutdownTimeout time.Duration
+	// ProxyHeader names the header holding the client IP when running
+	// behind a reverse proxy, e.g. X-Forwarded-For
+	ProxyHeader string
+	// AllowedOrigins may call the API from the browser; empty means
+	// same-origin only
+	AllowedOrigins        []string
+	ContentSecurityPolicy string
+	HSTSMaxAge            int
+	// AssetsDir loads views and static files from this website directory,
+	// reloading templates on every render; empty uses the embedded copies
+	AssetsDir string
 }
 
 type DatabaseConfig struct {
@@ -34,8 +52,9 @@ type DatabaseConfig struct {
 }
 
 type SupabaseConfig struct {
-	Url      string
-	Anon_key string
+	Url        string
+	Anon_key   string
+	Jwt_secret string
 }
 
 type RedisConfig struct {
@@ -45,6 +64,60 @@ type RedisConfig struct {
 	DB       int
 }
 
+type MetricsConfig struct {
+	RefreshInterval time.Duration
+	// Token is the bearer token scrapers must send; /metrics is not
+	// served without one
+	Token string
+}
+
+type TracingConfig struct {
+	ServiceName string
+	Exporter    string
+	Endpoint    string
+}
+
+type LogConfig struct {
+	Level  string
+	Format string
+	File   string
+}
+
+type RateLimitConfig struct {
+	Window        time.Duration
+	Anonymous     int
+	Authenticated int
+	Mutating      int
+}
+
+type CalendarConfig struct {
+	CacheTTL  time.Duration
+	CacheSize int
+}
+
+// Enabled reports whether a Redis server was configured
+func (r RedisConfig) Enabled() bool {
+	return r.Host != ""
+}
+
+// Addr returns the host:port of the Redis server
+func (r RedisConfig) Addr() string {
+	return r.Host + ":" + r.Port
+}
+
+// defaultContentSecurityPolicy only allows same-origin resources. Scripts
+// and stylesheets are all served from /static; the one exception is style
+// attributes, which carry each controller's calendar color.
+const defaultContentSecurityPolicy = "default-src 'self'; " +
+	"script-src 'self'; " +
+	"style-src 'self'; " +
+	"style-src-attr 'unsafe-inline'; " +
+	"img-src 'self' data:; " +
+	"object-src 'none'; " +
+	"base-uri 'self'; " +
+	"form-action 'self'; " +
+	"frame-ancestors 'none'"
+
 // LoadConfig loads configuration from environment variables
 func LoadConfig(envFile string) (*Config, error) {
 	// Load .env file if it exists
@@ -59,10 +132,16 @@ func LoadConfig(envFile string) (*Config, error) {
 
 	// Load server configuration
 	config.Server = ServerConfig{
-		Port:         getEnv("SERVER_PORT", "3000"),
-		Environment:  getEnv("ENVIRONMENT", "development"),
-		ReadTimeout:  getDurationEnv("SERVER_READ_TIMEOUT", 10*time.Second),
-		WriteTimeout: getDurationEnv("SERVER_WRITE_TIMEOUT", 10*time.Second),
+		Port:                  getEnv("SERVER_PORT", "3000"),
+		Environment:           getEnv("ENVIRONMENT", "development"),
+		ReadTimeout:           getDurationEnv("SERVER_READ_TIMEOUT", 10*time.Second),
+		WriteTimeout:          getDurationEnv("SERVER_WRITE_TIMEOUT", 10*time.Second),
+		ShutdownTimeout:       getDurationEnv("SERVER_SHUTDOWN_TIMEOUT", 15*time.Second),
+		ProxyHeader:           getEnv("SERVER_PROXY_HEADER", ""),
+		AllowedOrigins:        getListEnv("SERVER_ALLOWED_ORIGINS"),
+		ContentSecurityPolicy: getEnv("SERVER_CSP", defaultContentSecurityPolicy),
+		HSTSMaxAge:            getIntEnv("SERVER_HSTS_MAX_AGE", 31536000),
+		AssetsDir:             getEnv("SERVER_ASSETS_DIR", ""),
 	}
 
 	// Load database configuration
@@ -77,18 +156,53 @@ func LoadConfig(envFile string) (*Config, error) {
 
 	// Load Supabase configuration
 	config.Supabase = SupabaseConfig{
-		Url:      getEnv("SUPABASE_URL", ""),
-		Anon_key: getEnv("SUPABASE_ANON_KEY", ""),
+		Url:        getEnv("SUPABASE_URL", ""),
+		Anon_key:   getEnv("SUPABASE_ANON_KEY", ""),
+		Jwt_secret: getEnv("SUPABASE_JWT_SECRET", ""),
 	}
 
 	// Load Redis configuration
 	config.Redis = RedisConfig{
-		Host:     getEnv("REDIS_HOST", "localhost"),
+		Host:     getEnv("REDIS_HOST", ""),
 		Port:     getEnv("REDIS_PORT", "6379"),
 		Password: getEnv("REDIS_PASSWORD", ""),
 		DB:       getIntEnv("REDIS_DB", 0),
 	}
 
+	// Load metrics configuration
+	config.Metrics = MetricsConfig{
+		RefreshInterval: getDurationEnv("METRICS_REFRESH_INTERVAL", time.Minute),
+		Token:           getEnv("METRICS_TOKEN", ""),
+	}
+
+	// Load tracing configuration
+	config.Tracing = TracingConfig{
+		ServiceName: getEnv("OTEL_SERVICE_NAME", "weekend-warrior"),
+		Exporter:    getEnv("OTEL_TRACES_EXPORTER", ""),
+		Endpoint:    getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+	}
+
+	// Load logging configuration
+	config.Log = LogConfig{
+		Level:  getEnv("LOG_LEVEL", ""),
+		Format: getEnv("LOG_FORMAT", ""),
+		File:   getEnv("LOG_FILE", ""),
+	}
+
+	// Load rate limit configuration
+	config.RateLimit = RateLimitConfig{
+		Window:        getDurationEnv("RATE_LIMIT_WINDOW", time.Minute),
+		Anonymous:     getIntEnv("RATE_LIMIT_ANONYMOUS", 60),
+		Authenticated: getIntEnv("RATE_LIMIT_AUTHENTICATED", 300),
+		Mutating:      getIntEnv("RATE_LIMIT_MUTATING", 30),
+	}
+
+	// Load calendar configuration
+	config.Calendar = CalendarConfig{
+		CacheTTL:  getDurationEnv("CALENDAR_CACHE_TTL", time.Hour),
+		CacheSize: getIntEnv("CALENDAR_CACHE_SIZE", 512),
+	}
+
 	// Validate configuration
 	if err := config.Validate(); err != nil {
 		return nil, fmt.Errorf("config validation error: %w", err)
@@ -128,6 +242,17 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getListEnv splits a comma separated variable, dropping empty entries
+func getListEnv(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 func getIntEnv(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
 		if intValue, err := strconv.Atoi(value); err == nil {
@@ -0,0 +1,64 @@
+// pkg/metrics/domain.go
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/dukerupert/weekend-warrior/db"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	facilityControllers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "facility_controllers",
+		Help: "Active controllers at each active facility.",
+	}, []string{"facility"})
+
+	facilitySchedulesMissing = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "facility_schedules_missing",
+		Help: "Active controllers at each facility without a schedule.",
+	}, []string{"facility"})
+)
+
+func init() {
+	Registry.MustRegister(facilityControllers, facilitySchedulesMissing)
+}
+
+// RefreshDomain recomputes the domain gauges every interval until ctx is
+// cancelled. The queries are too heavy to run on every scrape.
+func RefreshDomain(ctx context.Context, dbService *db.Service, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		refreshDomain(ctx, dbService)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshDomain replaces the domain gauges with the current counts
+func refreshDomain(ctx context.Context, dbService *db.Service) {
+	staffing, err := dbService.GetFacilityStaffing(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Warn().Err(err).Msg("failed to refresh domain metrics")
+		}
+		return
+	}
+
+	// Reset so archived facilities drop out
+	facilityControllers.Reset()
+	facilitySchedulesMissing.Reset()
+
+	for _, fs := range staffing {
+		facilityControllers.WithLabelValues(fs.FacilityCode).Set(float64(fs.Controllers))
+		facilitySchedulesMissing.WithLabelValues(fs.FacilityCode).Set(float64(fs.MissingSchedules))
+	}
+}
@@ -0,0 +1,82 @@
+// pkg/metrics/metrics.go
+package metrics
+
+import (
+	"crypto/subtle"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Path is where metrics are served
+const Path = "/metrics"
+
+// Registry holds every collector exposed on Path
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests processed, by route and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency, by route and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	calendarDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "calendar_generation_duration_seconds",
+		Help:    "Time spent generating calendar data, by step.",
+		Buckets: []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05},
+	}, []string{"step"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		calendarDuration,
+	)
+}
+
+// ObserveRequest records a completed HTTP request. route should be the
+// matched route template, not the raw path, to keep label cardinality low.
+func ObserveRequest(method, route string, status int, latency time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(method, route, code).Inc()
+	httpDuration.WithLabelValues(method, route, code).Observe(latency.Seconds())
+}
+
+// ObserveCalendarGeneration records how long a calendar generation step
+// took since start
+func ObserveCalendarGeneration(step string, start time.Time) {
+	calendarDuration.WithLabelValues(step).Observe(time.Since(start).Seconds())
+}
+
+// Handler serves the registry in the Prometheus text format to scrapers
+// presenting token as a bearer token
+func Handler(token string) fiber.Handler {
+	serve := adaptor.HTTPHandler(promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+	want := []byte("Bearer " + token)
+
+	return func(c *fiber.Ctx) error {
+		got := []byte(c.Get(fiber.HeaderAuthorization))
+		if subtle.ConstantTimeCompare(got, want) != 1 {
+			c.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error":  "Authentication required",
+				"detail": "metrics require the scrape bearer token",
+			})
+		}
+		return serve(c)
+	}
+}
@@ -0,0 +1,42 @@
+package metrics_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dukerupert/weekend-warrior/pkg/metrics"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestHandlerRequiresToken(t *testing.T) {
+	app := fiber.New()
+	app.Get(metrics.Path, metrics.Handler("scrape-secret"))
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"no token", "", fiber.StatusUnauthorized},
+		{"wrong token", "Bearer guess", fiber.StatusUnauthorized},
+		{"token without scheme", "scrape-secret", fiber.StatusUnauthorized},
+		{"scrape token", "Bearer scrape-secret", fiber.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodGet, metrics.Path, nil)
+			if tt.authorization != "" {
+				req.Header.Set(fiber.HeaderAuthorization, tt.authorization)
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
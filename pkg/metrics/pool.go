@@ -0,0 +1,62 @@
+// pkg/metrics/pool.go
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector reports pgxpool statistics at scrape time
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquired        *prometheus.Desc
+	idle            *prometheus.Desc
+	total           *prometheus.Desc
+	max             *prometheus.Desc
+	acquires        *prometheus.Desc
+	acquireDuration *prometheus.Desc
+	emptyAcquires   *prometheus.Desc
+	canceled        *prometheus.Desc
+}
+
+// RegisterPool exposes the statistics of a pgx connection pool
+func RegisterPool(pool *pgxpool.Pool) {
+	Registry.MustRegister(&poolCollector{
+		pool:            pool,
+		acquired:        prometheus.NewDesc("pgxpool_acquired_conns", "Connections currently checked out of the pool.", nil, nil),
+		idle:            prometheus.NewDesc("pgxpool_idle_conns", "Idle connections in the pool.", nil, nil),
+		total:           prometheus.NewDesc("pgxpool_total_conns", "Total connections in the pool.", nil, nil),
+		max:             prometheus.NewDesc("pgxpool_max_conns", "Maximum size of the pool.", nil, nil),
+		acquires:        prometheus.NewDesc("pgxpool_acquires_total", "Successful connection acquires.", nil, nil),
+		acquireDuration: prometheus.NewDesc("pgxpool_acquire_wait_seconds_total", "Total time spent waiting to acquire a connection.", nil, nil),
+		emptyAcquires:   prometheus.NewDesc("pgxpool_empty_acquires_total", "Acquires that had to wait because the pool was empty.", nil, nil),
+		canceled:        prometheus.NewDesc("pgxpool_canceled_acquires_total", "Acquires canceled by their context.", nil, nil),
+	})
+}
+
+// Describe implements prometheus.Collector
+func (pc *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- pc.acquired
+	ch <- pc.idle
+	ch <- pc.total
+	ch <- pc.max
+	ch <- pc.acquires
+	ch <- pc.acquireDuration
+	ch <- pc.emptyAcquires
+	ch <- pc.canceled
+}
+
+// Collect implements prometheus.Collector
+func (pc *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := pc.pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(pc.acquired, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(pc.idle, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(pc.total, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(pc.max, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(pc.acquires, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(pc.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(pc.emptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(pc.canceled, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+}
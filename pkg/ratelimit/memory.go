@@ -0,0 +1,58 @@
+// pkg/ratelimit/memory.go
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often expired windows are dropped from memory
+const sweepInterval = time.Minute
+
+// MemoryStore keeps counters in process memory. It is only correct when a
+// single instance serves all traffic.
+type MemoryStore struct {
+	mu        sync.Mutex
+	windows   map[string]*window
+	lastSweep time.Time
+}
+
+type window struct {
+	count   int
+	expires time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		windows:   make(map[string]*window),
+		lastSweep: time.Now(),
+	}
+}
+
+// Hit implements Store
+func (m *MemoryStore) Hit(_ context.Context, key string, length time.Duration) (int, time.Duration, error) {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if now.Sub(m.lastSweep) >= sweepInterval {
+		for k, w := range m.windows {
+			if !now.Before(w.expires) {
+				delete(m.windows, k)
+			}
+		}
+		m.lastSweep = now
+	}
+
+	w, ok := m.windows[key]
+	if !ok || !now.Before(w.expires) {
+		w = &window{expires: now.Add(length)}
+		m.windows[key] = w
+	}
+	w.count++
+
+	return w.count, w.expires.Sub(now), nil
+}
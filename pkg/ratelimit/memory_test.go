@@ -0,0 +1,55 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dukerupert/weekend-warrior/pkg/ratelimit"
+)
+
+func TestMemoryStoreCountsPerKey(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	ctx := context.Background()
+
+	for want := 1; want <= 3; want++ {
+		count, reset, err := store.Hit(ctx, "a", time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != want {
+			t.Errorf("hit %d: count = %d", want, count)
+		}
+		if reset <= 0 || reset > time.Minute {
+			t.Errorf("hit %d: reset = %v, want within the window", want, reset)
+		}
+	}
+
+	if count, _, _ := store.Hit(ctx, "b", time.Minute); count != 1 {
+		t.Errorf("other key: count = %d, want 1", count)
+	}
+}
+
+func TestMemoryStoreWindowResets(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	ctx := context.Background()
+	window := 20 * time.Millisecond
+
+	store.Hit(ctx, "a", window)
+	if count, _, _ := store.Hit(ctx, "a", window); count != 2 {
+		t.Fatalf("count = %d within the window, want 2", count)
+	}
+
+	time.Sleep(window + 5*time.Millisecond)
+
+	count, reset, err := store.Hit(ctx, "a", window)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("count = %d after the window ended, want a fresh count of 1", count)
+	}
+	if reset <= 0 || reset > window {
+		t.Errorf("reset = %v, want a new window of at most %v", reset, window)
+	}
+}
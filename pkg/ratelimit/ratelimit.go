@@ -0,0 +1,14 @@
+// pkg/ratelimit/ratelimit.go
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Store counts hits per key in fixed windows
+type Store interface {
+	// Hit records one request for key and returns the number of requests
+	// seen in the current window and the time until that window resets
+	Hit(ctx context.Context, key string, window time.Duration) (count int, reset time.Duration, err error)
+}
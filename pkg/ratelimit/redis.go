@@ -0,0 +1,46 @@
+// pkg/ratelimit/redis.go
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// hitScript increments a counter and starts its window on the first hit,
+// atomically so concurrent instances agree on when the window resets
+var hitScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+    redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return {count, redis.call('PTTL', KEYS[1])}
+`)
+
+// RedisStore keeps counters in Redis so limits hold across instances
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore creates a store that namespaces its keys with prefix
+func NewRedisStore(client *redis.Client, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// Hit implements Store
+func (r *RedisStore) Hit(ctx context.Context, key string, window time.Duration) (int, time.Duration, error) {
+	result, err := hitScript.Run(ctx, r.client, []string{r.prefix + key}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, 0, fmt.Errorf("error counting rate limit hit: %w", err)
+	}
+
+	reset := time.Duration(result[1]) * time.Millisecond
+	if reset < 0 {
+		reset = window
+	}
+
+	return int(result[0]), reset, nil
+}
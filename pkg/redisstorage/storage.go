@@ -0,0 +1,72 @@
+// pkg/redisstorage/storage.go
+package redisstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Storage implements fiber.Storage on Redis so middleware state such as
+// CSRF tokens is shared between instances. Keys are namespaced by prefix.
+type Storage struct {
+	client *redis.Client
+	prefix string
+}
+
+// New creates a Storage that keeps its keys under prefix
+func New(client *redis.Client, prefix string) *Storage {
+	return &Storage{client: client, prefix: prefix}
+}
+
+// Get returns the value for key, or nil when it does not exist
+func (s *Storage) Get(key string) ([]byte, error) {
+	val, err := s.client.Get(context.Background(), s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting %s from redis: %w", key, err)
+	}
+	return val, nil
+}
+
+// Set stores val under key. A zero exp means the key does not expire.
+func (s *Storage) Set(key string, val []byte, exp time.Duration) error {
+	if err := s.client.Set(context.Background(), s.prefix+key, val, exp).Err(); err != nil {
+		return fmt.Errorf("error setting %s in redis: %w", key, err)
+	}
+	return nil
+}
+
+// Delete removes key
+func (s *Storage) Delete(key string) error {
+	if err := s.client.Del(context.Background(), s.prefix+key).Err(); err != nil {
+		return fmt.Errorf("error deleting %s from redis: %w", key, err)
+	}
+	return nil
+}
+
+// Reset removes every key under the prefix, leaving the rest of the
+// database alone
+func (s *Storage) Reset() error {
+	ctx := context.Background()
+	iter := s.client.Scan(ctx, 0, s.prefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		if err := s.client.Del(ctx, iter.Val()).Err(); err != nil {
+			return fmt.Errorf("error resetting redis storage: %w", err)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("error resetting redis storage: %w", err)
+	}
+	return nil
+}
+
+// Close is a no-op, the client is owned by the app
+func (s *Storage) Close() error {
+	return nil
+}
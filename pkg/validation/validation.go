@@ -0,0 +1,130 @@
+// pkg/validation/validation.go
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldErrors maps a JSON field name to a human readable problem with it
+type FieldErrors map[string]string
+
+// Error implements the error interface
+func (fe FieldErrors) Error() string {
+	fields := make([]string, 0, len(fe))
+	for field := range fe {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	parts := make([]string, 0, len(fields))
+	for _, field := range fields {
+		parts = append(parts, fmt.Sprintf("%s %s", field, fe[field]))
+	}
+	return strings.Join(parts, "; ")
+}
+
+var validate = newValidator()
+
+// newValidator creates a validator that reports fields by their JSON names
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v
+}
+
+// Struct validates s against its `validate` tags. It returns nil when s is
+// valid and FieldErrors otherwise.
+func Struct(s interface{}) error {
+	err := validate.Struct(s)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+
+	fields := FieldErrors{}
+	for _, fieldErr := range validationErrors {
+		name := fieldName(fieldErr)
+		if _, seen := fields[name]; seen {
+			continue
+		}
+		fields[name] = message(fieldErr)
+	}
+
+	return fields
+}
+
+// fieldName returns the top-level JSON field name, so errors on slice
+// elements such as rdos[2] are reported against rdos
+func fieldName(fieldErr validator.FieldError) string {
+	name := fieldErr.Field()
+	if i := strings.Index(name, "["); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// message translates a failed validation tag into a readable message
+func message(fieldErr validator.FieldError) string {
+	isElement := strings.Contains(fieldErr.Field(), "[")
+	isString := fieldErr.Kind() == reflect.String
+
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "alpha":
+		return "must contain only letters"
+	case "alphanum":
+		return "must contain only letters and numbers"
+	case "unique":
+		return "must not contain duplicates"
+	case "len":
+		if isString {
+			return fmt.Sprintf("must be exactly %s characters", fieldErr.Param())
+		}
+		return fmt.Sprintf("must contain exactly %s items", fieldErr.Param())
+	case "min", "gte":
+		if isElement {
+			return fmt.Sprintf("values must be at least %s", fieldErr.Param())
+		}
+		if isString {
+			return fmt.Sprintf("must be at least %s characters", fieldErr.Param())
+		}
+		if fieldErr.Kind() == reflect.Slice {
+			return fmt.Sprintf("must contain at least %s items", fieldErr.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fieldErr.Param())
+	case "max", "lte":
+		if isElement {
+			return fmt.Sprintf("values must be at most %s", fieldErr.Param())
+		}
+		if isString {
+			return fmt.Sprintf("must be at most %s characters", fieldErr.Param())
+		}
+		if fieldErr.Kind() == reflect.Slice {
+			return fmt.Sprintf("must contain at most %s items", fieldErr.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fieldErr.Param())
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fieldErr.Param())
+	default:
+		return fmt.Sprintf("failed the %s check", fieldErr.Tag())
+	}
+}
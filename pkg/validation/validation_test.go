@@ -0,0 +1,134 @@
+package validation_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dukerupert/weekend-warrior/db/models"
+	"github.com/dukerupert/weekend-warrior/pkg/validation"
+)
+
+var anchor = time.Date(2025, time.January, 6, 0, 0, 0, 0, time.UTC)
+
+// TestStructMessages checks the message each failed tag is reported with,
+// against the request types the handlers validate
+func TestStructMessages(t *testing.T) {
+	tests := []struct {
+		name  string
+		input interface{}
+		want  validation.FieldErrors
+	}{
+		{
+			name:  "valid controller",
+			input: models.CreateControllerParams{Name: "Jane Doe", Initials: "JD", Email: "jd@example.com", FacilityID: 1},
+			want:  nil,
+		},
+		{
+			name:  "required",
+			input: models.CreateControllerParams{Initials: "JD", Email: "jd@example.com", FacilityID: 1},
+			want:  validation.FieldErrors{"name": "is required"},
+		},
+		{
+			name:  "email",
+			input: models.CreateControllerParams{Name: "Jane Doe", Initials: "JD", Email: "jane", FacilityID: 1},
+			want:  validation.FieldErrors{"email": "must be a valid email address"},
+		},
+		{
+			name:  "len on a string and alpha",
+			input: models.CreateControllerParams{Name: "Jane Doe", Initials: "J1", Email: "jd@example.com", FacilityID: 1},
+			want:  validation.FieldErrors{"initials": "must contain only letters"},
+		},
+		{
+			name:  "len on a string",
+			input: models.CreateFacilityParams{Name: "Seattle Center", Code: "ZSE"},
+			want:  validation.FieldErrors{"code": "must be exactly 4 characters"},
+		},
+		{
+			name:  "alphanum",
+			input: models.CreateFacilityParams{Name: "Seattle Center", Code: "ZS-E"},
+			want:  validation.FieldErrors{"code": "must contain only letters and numbers"},
+		},
+		{
+			name:  "max on a string",
+			input: models.UpdateFacilityParams{Name: string(make([]byte, 101)), Code: "ZSEA"},
+			want:  validation.FieldErrors{"name": "must be at most 100 characters"},
+		},
+		{
+			name:  "every failing field is reported",
+			input: models.CreateControllerParams{},
+			want: validation.FieldErrors{
+				"name":        "is required",
+				"initials":    "must be exactly 2 characters",
+				"email":       "is required",
+				"facility_id": "is required",
+			},
+		},
+		{
+			name:  "valid schedule",
+			input: models.CreateScheduleParams{RDOs: []int{0, 6}, Anchor: anchor, ControllerID: 1},
+			want:  nil,
+		},
+		{
+			name:  "one RDO is not a pair",
+			input: models.CreateScheduleParams{RDOs: []int{0}, Anchor: anchor, ControllerID: 1},
+			want:  validation.FieldErrors{"rdos": "must contain at least 2 items"},
+		},
+		{
+			name:  "more RDOs than weekdays",
+			input: models.UpdateScheduleParams{RDOs: []int{0, 1, 2, 3, 4, 5, 6, 0}, Anchor: anchor},
+			want:  validation.FieldErrors{"rdos": "must contain at most 7 items"},
+		},
+		{
+			name:  "unique",
+			input: models.UpdateScheduleParams{RDOs: []int{3, 3}, Anchor: anchor},
+			want:  validation.FieldErrors{"rdos": "must not contain duplicates"},
+		},
+		{
+			name:  "min on an element",
+			input: models.UpdateScheduleParams{RDOs: []int{-1, 3}, Anchor: anchor},
+			want:  validation.FieldErrors{"rdos": "values must be at least 0"},
+		},
+		{
+			name:  "max on an element",
+			input: models.UpdateScheduleParams{RDOs: []int{3, 7}, Anchor: anchor},
+			want:  validation.FieldErrors{"rdos": "values must be at most 6"},
+		},
+		{
+			name:  "required time and ID",
+			input: models.CreateScheduleParams{RDOs: []int{0, 6}},
+			want: validation.FieldErrors{
+				"anchor":        "is required",
+				"controller_id": "is required",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validation.Struct(tt.input)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Struct() = %v, want nil", err)
+				}
+				return
+			}
+
+			var got validation.FieldErrors
+			if !errors.As(err, &got) {
+				t.Fatalf("Struct() = %v, want FieldErrors", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Struct() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldErrorsError(t *testing.T) {
+	err := validation.FieldErrors{"rdos": "is required", "anchor": "is required"}
+	if got, want := err.Error(), "anchor is required; rdos is required"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
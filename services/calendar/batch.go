@@ -0,0 +1,166 @@
+// services/calendar/batch.go
+package calendar
+
+import (
+	"context"
+	"time"
+
+	"github.com/dukerupert/weekend-warrior/db/models"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// rotation describes a schedule's weekday pairs as day numbers, so whether
+// a date falls on a pair is plain arithmetic instead of a lookup in a
+// year's worth of generated pairs. It follows GenerateWeekdayPairs: the
+// first pair starts on the first RDO weekday on or after the anchor, pairs
+// repeat weekly and every third pair is protected. Unlike the generated
+// pairs, which stop a year after the anchor, the rotation never ends, so a
+// schedule keeps its days off until it is changed.
+type rotation struct {
+	firstDay     int64 // day number of the first day of the first pair
+	secondOffset int64 // days from a pair's first day to its second day
+}
+
+// dayNumber counts days since the Unix epoch for a calendar date
+func dayNumber(year int, month time.Month, day int) int64 {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix() / 86400
+}
+
+// newRotation derives the rotation of a schedule, or false when the
+// schedule does not name a pair of weekdays
+func newRotation(schedule models.Schedule) (rotation, bool) {
+	first, second, ok := schedulePair(&schedule)
+	if !ok {
+		return rotation{}, false
+	}
+
+	anchor := schedule.Anchor
+	anchorDay := dayNumber(anchor.Year(), anchor.Month(), anchor.Day())
+
+	daysUntilFirst := (int64(first) - int64(anchor.Weekday()) + 7) % 7
+	secondOffset := (int64(second) - int64(first) + 7) % 7
+	if secondOffset == 0 {
+		secondOffset = 7
+	}
+
+	return rotation{
+		firstDay:     anchorDay + daysUntilFirst,
+		secondOffset: secondOffset,
+	}, true
+}
+
+// state reports whether day falls on a pair and whether that pair is
+// protected. When both RDOs name the same weekday a day can end one pair
+// and start the next; it is protected if either pair is.
+func (r rotation) state(day int64) (hasPair, protected bool) {
+	for _, start := range [2]int64{day - r.firstDay, day - r.firstDay - r.secondOffset} {
+		if start < 0 || start%7 != 0 {
+			continue
+		}
+		hasPair = true
+		protected = protected || start/7%3 == 0
+	}
+	return hasPair, protected
+}
+
+// GenerateFacilityCalendars builds one month calendar per controller
+// schedule in a single pass, computing each day's state from the schedule's
+// anchor and RDOs. Calendars are colored in the order given.
+func (s *Service) GenerateFacilityCalendars(ctx context.Context, year, month int, schedules []models.ControllerSchedule) []Calendar {
+	_, done := s.step(ctx, "facility_month",
+		attribute.Int("calendar.year", year),
+		attribute.Int("calendar.month", month),
+		attribute.Int("calendar.controllers", len(schedules)),
+	)
+	defer done()
+
+	now := time.Now()
+	firstWeekday := s.FirstDayOfMonth(year, month)
+	totalDays := s.DaysInMonth(year, month)
+	monthStart := dayNumber(year, time.Month(month), 1)
+	todayDay := 0
+	if now.Year() == year && int(now.Month()) == month {
+		todayDay = now.Day()
+	}
+
+	calendars := make([]Calendar, 0, len(schedules))
+	for _, cs := range schedules {
+		rot, ok := newRotation(cs.Schedule)
+		if !ok {
+			continue
+		}
+
+		cal := Calendar{
+			Year:         year,
+			Month:        month,
+			MonthName:    s.getMonthName(month),
+			Days:         make([][]CalendarDay, 6),
+			Color:        s.generateColor(len(calendars)),
+			Initials:     cs.Controller.Initials,
+			ControllerID: cs.Controller.ID,
+		}
+
+		// One backing array per calendar keeps allocations flat
+		cells := make([]CalendarDay, 6*7)
+		for i := range cal.Days {
+			cal.Days[i] = cells[i*7 : (i+1)*7 : (i+1)*7]
+		}
+
+		for day := 1; day <= totalDays; day++ {
+			cell := firstWeekday + day - 1
+			hasPair, protected := rot.state(monthStart + int64(day-1))
+			cells[cell] = CalendarDay{
+				Day:       day,
+				IsToday:   day == todayDay,
+				HasPair:   hasPair,
+				Protected: protected,
+			}
+		}
+
+		calendars = append(calendars, cal)
+	}
+
+	return calendars
+}
+
+// GenerateMonths builds count consecutive months for the given schedules,
+// starting at year and month. Each element holds one month's calendars as
+// GenerateFacilityCalendars returns them.
+func (s *Service) GenerateMonths(ctx context.Context, year, month, count int, schedules []models.ControllerSchedule) [][]Calendar {
+	months := make([][]Calendar, 0, count)
+	for i := 0; i < count; i++ {
+		start := time.Date(year, time.Month(month+i), 1, 0, 0, 0, 0, time.UTC)
+		months = append(months, s.GenerateFacilityCalendars(ctx, start.Year(), int(start.Month()), schedules))
+	}
+	return months
+}
+
+// ProtectedPairs counts the protected pairs of a schedule that start in
+// year
+func (s *Service) ProtectedPairs(schedule models.Schedule, year int) int {
+	rot, ok := newRotation(schedule)
+	if !ok {
+		return 0
+	}
+
+	from := dayNumber(year, time.January, 1)
+	to := dayNumber(year+1, time.January, 1)
+
+	count := 0
+	for start := rot.firstDay; start < to; start += 3 * 7 {
+		if start >= from {
+			count++
+		}
+	}
+	return count
+}
+
+// DayOff reports whether date falls on one of the schedule's pairs and
+// whether that pair is protected
+func (s *Service) DayOff(schedule models.Schedule, date time.Time) (off, protected bool) {
+	rot, ok := newRotation(schedule)
+	if !ok {
+		return false, false
+	}
+	return rot.state(dayNumber(date.Year(), date.Month(), date.Day()))
+}
@@ -0,0 +1,161 @@
+package calendar
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dukerupert/weekend-warrior/db/models"
+)
+
+// syntheticSchedules returns count controllers with schedules spread over
+// every weekday pair and a range of anchors
+func syntheticSchedules(count int, anchor time.Time) []models.ControllerSchedule {
+	schedules := make([]models.ControllerSchedule, count)
+	for i := range schedules {
+		first := i % 7
+		second := (i/7 + first + 1) % 7
+		schedules[i] = models.ControllerSchedule{
+			Controller: models.Controller{
+				ID:       i + 1,
+				Initials: string([]byte{'A' + byte(i%26), 'A' + byte(i/26%26)}),
+			},
+			Schedule: models.Schedule{
+				RDOs:   []int{first, second},
+				Anchor: anchor.AddDate(0, 0, -i%60),
+			},
+		}
+	}
+	return schedules
+}
+
+var testAnchors = []time.Time{
+	time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC),
+	time.Date(2024, time.December, 30, 0, 0, 0, 0, time.UTC),
+	time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(2025, time.September, 17, 0, 0, 0, 0, time.UTC),
+}
+
+// everyPair returns a schedule for each ordered pair of weekdays, including
+// both RDOs on the same weekday
+func everyPair(anchor time.Time) []models.ControllerSchedule {
+	var schedules []models.ControllerSchedule
+	for first := 0; first < 7; first++ {
+		for second := 0; second < 7; second++ {
+			schedules = append(schedules, models.ControllerSchedule{
+				Controller: models.Controller{ID: len(schedules) + 1, Initials: "XY"},
+				Schedule:   models.Schedule{RDOs: []int{first, second}, Anchor: anchor},
+			})
+		}
+	}
+	return schedules
+}
+
+// TestGenerateFacilityCalendarsMatchesPairs checks the rotation arithmetic
+// against the original path, which generates a year of pairs per schedule
+// and looks each day up in them
+func TestGenerateFacilityCalendarsMatchesPairs(t *testing.T) {
+	testMatchesPairs(t, 0, func(anchor, _ time.Time) time.Time { return anchor })
+}
+
+// TestGenerateFacilityCalendarsContinues checks the months after the first
+// year against pairs generated from a later anchor. Moving an anchor by
+// whole three week cycles keeps both the pairs and their protection, so the
+// rotation must carry on as if the schedule had been anchored then.
+func TestGenerateFacilityCalendarsContinues(t *testing.T) {
+	testMatchesPairs(t, 12, func(anchor, start time.Time) time.Time {
+		// The cycle that starts between 30 and 50 days before the month, so
+		// its year of pairs covers the month and the pair running into it
+		cycles := (start.Sub(anchor).Hours()/24 - 30) / 21
+		return anchor.AddDate(0, 0, int(cycles)*21)
+	})
+}
+
+// testMatchesPairs compares a facility's calendars with the original path,
+// from the month firstOffset months after each anchor to the year after.
+// pairsAnchor picks the anchor the reference pairs for the month starting
+// at start are generated from.
+func testMatchesPairs(t *testing.T, firstOffset int, pairsAnchor func(anchor, start time.Time) time.Time) {
+	t.Helper()
+	s := &Service{}
+	ctx := context.Background()
+
+	for _, anchor := range testAnchors {
+		schedules := everyPair(anchor)
+
+		// The generated pairs end a year after their anchor, so a month
+		// from before it to the last whole month of pairs
+		for offset := firstOffset - 1; offset <= firstOffset+11; offset++ {
+			start := time.Date(anchor.Year(), anchor.Month()+time.Month(offset), 1, 0, 0, 0, 0, time.UTC)
+			year, month := start.Year(), int(start.Month())
+
+			got := s.GenerateFacilityCalendars(ctx, year, month, schedules)
+			if len(got) != len(schedules) {
+				t.Fatalf("%d-%02d: got %d calendars, want %d", year, month, len(got), len(schedules))
+			}
+
+			for i, cs := range schedules {
+				first, second, _ := schedulePair(&cs.Schedule)
+				pairs := s.GenerateWeekdayPairs(ctx, first, second, pairsAnchor(anchor, start))
+				want := s.GenerateCalendar(ctx, year, month, pairs, cs.Controller.Initials, i)
+
+				if !reflect.DeepEqual(got[i].Days, want.Days) {
+					t.Errorf("anchor %s, RDOs %v, %d-%02d: days differ\n got %v\nwant %v",
+						anchor.Format(time.DateOnly), cs.Schedule.RDOs, year, month, got[i].Days, want.Days)
+				}
+				if got[i].Color != want.Color || got[i].Initials != want.Initials {
+					t.Errorf("anchor %s, RDOs %v: legend differs", anchor.Format(time.DateOnly), cs.Schedule.RDOs)
+				}
+			}
+		}
+	}
+}
+
+func TestProtectedPairs(t *testing.T) {
+	s := &Service{}
+
+	// Pairs start every Saturday from the anchor; every third is protected
+	schedule := models.Schedule{
+		RDOs:   []int{int(time.Saturday), int(time.Sunday)},
+		Anchor: time.Date(2024, time.January, 6, 0, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		year int
+		want int
+	}{
+		{year: 2023, want: 0},
+		// The 52 Saturdays from the anchor are weeks 0 to 51
+		{year: 2024, want: 18},
+		// Weeks 52 to 103, past the year the pairs were once cut off at
+		{year: 2025, want: 17},
+		{year: 2026, want: 17},
+		// The first Saturday of 2034 is week 522, a protected one
+		{year: 2034, want: 18},
+	}
+
+	for _, tt := range tests {
+		if got := s.ProtectedPairs(schedule, tt.year); got != tt.want {
+			t.Errorf("ProtectedPairs(%d) = %d, want %d", tt.year, got, tt.want)
+		}
+	}
+
+	if got := s.ProtectedPairs(models.Schedule{RDOs: []int{6}, Anchor: schedule.Anchor}, 2024); got != 0 {
+		t.Errorf("ProtectedPairs() without a pair = %d, want 0", got)
+	}
+}
+
+// BenchmarkGenerateFacilityCalendars renders one month for a facility of
+// 500 controllers
+func BenchmarkGenerateFacilityCalendars(b *testing.B) {
+	s := &Service{}
+	ctx := context.Background()
+	schedules := syntheticSchedules(500, time.Date(2025, time.January, 6, 0, 0, 0, 0, time.UTC))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.GenerateFacilityCalendars(ctx, 2025, int(time.July), schedules)
+	}
+}
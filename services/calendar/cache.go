@@ -0,0 +1,42 @@
+// services/calendar/cache.go
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Cache stores generated facility months. Every facility has a generation
+// that is part of the key, so bumping it makes all of the facility's cached
+// months unreachable at once; they then age out through the TTL or LRU.
+type Cache interface {
+	// Get returns the calendars cached under key
+	Get(ctx context.Context, key string) ([]Calendar, bool)
+	// Set caches calendars under key
+	Set(ctx context.Context, key string, calendars []Calendar)
+	// Generation returns the current generation of a facility
+	Generation(ctx context.Context, facilityID int) int64
+	// Bump moves a facility to a new generation
+	Bump(ctx context.Context, facilityID int) error
+}
+
+// monthKey identifies one facility month at one schedule generation
+func monthKey(facilityID, year, month int, generation int64) string {
+	return fmt.Sprintf("calendar:month:%d:%04d-%02d:%d", facilityID, year, month, generation)
+}
+
+// markToday refreshes the IsToday flags, which go stale while a month sits
+// in the cache
+func markToday(calendars []Calendar, now time.Time) {
+	for i := range calendars {
+		cal := &calendars[i]
+		isCurrentMonth := cal.Year == now.Year() && cal.Month == int(now.Month())
+		for w := range cal.Days {
+			for d := range cal.Days[w] {
+				day := &cal.Days[w][d]
+				day.IsToday = isCurrentMonth && day.Day == now.Day()
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package calendar
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// testCacheGenerations checks that bumping a facility's generation hides
+// its cached months without touching other facilities
+func testCacheGenerations(t *testing.T, cache Cache, facilityID, otherID int) {
+	ctx := context.Background()
+	s := &Service{cache: cache}
+
+	key := monthKey(facilityID, 2025, 7, cache.Generation(ctx, facilityID))
+	otherKey := monthKey(otherID, 2025, 7, cache.Generation(ctx, otherID))
+	cache.Set(ctx, key, month(1))
+	cache.Set(ctx, otherKey, month(2))
+
+	// FacilityMonth serves the cached month without reaching the database
+	got, err := s.FacilityMonth(ctx, facilityID, 2025, 7)
+	if err != nil || len(got) != 1 || got[0].ControllerID != 1 {
+		t.Fatalf("FacilityMonth() = %v, %v, want the cached month", got, err)
+	}
+
+	before := cache.Generation(ctx, facilityID)
+	s.InvalidateFacility(ctx, facilityID)
+	after := cache.Generation(ctx, facilityID)
+	if after == before {
+		t.Fatalf("generation stayed %d after InvalidateFacility", after)
+	}
+
+	newKey := monthKey(facilityID, 2025, 7, after)
+	if newKey == key {
+		t.Fatalf("month key %q did not change with the generation", key)
+	}
+	if _, ok := cache.Get(ctx, newKey); ok {
+		t.Error("the bumped facility still has a cached month")
+	}
+
+	otherNow := monthKey(otherID, 2025, 7, cache.Generation(ctx, otherID))
+	if otherNow != otherKey {
+		t.Errorf("bumping facility %d moved facility %d to %q", facilityID, otherID, otherNow)
+	}
+	if _, ok := cache.Get(ctx, otherNow); !ok {
+		t.Error("bumping one facility dropped another facility's month")
+	}
+}
+
+func TestLRUCacheGenerations(t *testing.T) {
+	testCacheGenerations(t, NewLRUCache(8, time.Hour), 1, 2)
+}
+
+// TestRedisCacheGenerations runs against the Redis at TEST_REDIS_ADDR, e.g.
+// localhost:6379, and is skipped without one
+func TestRedisCacheGenerations(t *testing.T) {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR not set")
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	t.Cleanup(func() { client.Close() })
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Fatalf("cannot reach Redis at %s: %v", addr, err)
+	}
+
+	// Facility IDs no real data uses, unique per run
+	facilityID := -int(time.Now().UnixNano() % 1e9)
+	testCacheGenerations(t, NewRedisCache(client, time.Minute), facilityID, facilityID-1)
+}
+
+// TestRedisCacheUnavailable checks that Redis failures read as misses so
+// pages fall back to generating the month
+func TestRedisCacheUnavailable(t *testing.T) {
+	ctx := context.Background()
+	client := redis.NewClient(&redis.Options{
+		Addr:        "127.0.0.1:1",
+		DialTimeout: 50 * time.Millisecond,
+		MaxRetries:  -1,
+	})
+	t.Cleanup(func() { client.Close() })
+	cache := NewRedisCache(client, time.Minute)
+
+	cache.Set(ctx, "a", month(1))
+	if _, ok := cache.Get(ctx, "a"); ok {
+		t.Error("Get() hit with Redis unreachable")
+	}
+	if got := cache.Generation(ctx, 1); got != 0 {
+		t.Errorf("Generation() = %d, want 0", got)
+	}
+	if err := cache.Bump(ctx, 1); err == nil {
+		t.Error("Bump() succeeded with Redis unreachable")
+	}
+}
@@ -1,21 +1,31 @@
 package calendar
 
 import (
+    "context"
     "fmt"
     "html/template"
     "time"
-    "github.com/jackc/pgx/v5/pgxpool"
+    "github.com/dukerupert/weekend-warrior/db"
+    "github.com/dukerupert/weekend-warrior/pkg/metrics"
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/dukerupert/weekend-warrior/services/calendar")
+
 // Service handles all calendar-related business logic
 type Service struct {
-    db *pgxpool.Pool
+    db    *db.Service
+    cache Cache
 }
 
-// NewService creates a new calendar service
-func NewService(db *pgxpool.Pool) *Service {
+// NewService creates a new calendar service. Facility months are cached in
+// cache, which must not be nil.
+func NewService(dbService *db.Service, cache Cache) *Service {
     return &Service{
-        db: db,
+        db:    dbService,
+        cache: cache,
     }
 }
 
@@ -29,12 +39,13 @@ type CalendarDay struct {
 
 // Calendar represents a complete month calendar structure
 type Calendar struct {
-    Year      int
-    Month     int
-    Days      [][]CalendarDay
-    MonthName string
-    Color     template.CSS    // HSL color for this calendar's pairs
-    Initials  string    // Two-letter initials for the legend
+    Year         int
+    Month        int
+    Days         [][]CalendarDay
+    MonthName    string
+    Color        template.CSS // HSL color for this calendar's pairs
+    Initials     string       // Two-letter initials for the legend
+    ControllerID int          // Controller the pairs belong to, 0 for a bare grid
 }
 
 // WeekdayPair represents a pair of weekdays
@@ -44,6 +55,17 @@ type WeekdayPair struct {
     Protected bool
 }
 
+// step starts a span for one generation step and returns a function that
+// ends it and records the step's duration
+func (s *Service) step(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, func()) {
+    start := time.Now()
+    ctx, span := tracer.Start(ctx, "calendar."+name, trace.WithAttributes(attrs...))
+    return ctx, func() {
+        span.End()
+        metrics.ObserveCalendarGeneration(name, start)
+    }
+}
+
 // generateColor creates a pleasing HSL color based on an index
 func (s *Service) generateColor(index int) template.CSS {
     // Use golden ratio for even color distribution
@@ -59,7 +81,12 @@ func (s *Service) generateColor(index int) template.CSS {
 }
 
 // GenerateWeekdayPairs generates pairs of weekdays for a year from the anchor date
-func (s *Service) GenerateWeekdayPairs(firstWeekday, secondWeekday time.Weekday, anchorDate time.Time) []WeekdayPair {
+func (s *Service) GenerateWeekdayPairs(ctx context.Context, firstWeekday, secondWeekday time.Weekday, anchorDate time.Time) []WeekdayPair {
+    _, done := s.step(ctx, "pairs",
+        attribute.String("calendar.anchor", anchorDate.Format(time.DateOnly)),
+    )
+    defer done()
+
     var pairs []WeekdayPair
     
     // Normalize time to midnight to ensure consistent date handling
@@ -172,7 +199,14 @@ func (s *Service) FirstDayOfMonth(year int, month int) int {
 }
 
 // GenerateCalendar creates a calendar structure for a specific pair set
-func (s *Service) GenerateCalendar(year, month int, pairs []WeekdayPair, initials string, colorIndex int) Calendar {
+func (s *Service) GenerateCalendar(ctx context.Context, year, month int, pairs []WeekdayPair, initials string, colorIndex int) Calendar {
+    _, done := s.step(ctx, "month",
+        attribute.Int("calendar.year", year),
+        attribute.Int("calendar.month", month),
+        attribute.String("calendar.initials", initials),
+    )
+    defer done()
+
     // Get the current date for comparing with today
     currentYear, currentMonth := s.GetCurrentYearMonth()
     currentDay := time.Now().Day()
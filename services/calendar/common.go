@@ -0,0 +1,80 @@
+// services/calendar/common.go
+package calendar
+
+import (
+	"context"
+	"time"
+
+	"github.com/dukerupert/weekend-warrior/db/models"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// CommonDay is a date when enough of a group of controllers are off
+type CommonDay struct {
+	Date time.Time `json:"date"`
+	// Off lists the IDs of the controllers off that day
+	Off []int `json:"off"`
+	// Protected lists those of them whose pair is protected
+	Protected []int `json:"protected"`
+	// ProtectedForAll reports whether every controller in the group is off
+	// on a protected pair
+	ProtectedForAll bool `json:"protected_for_all"`
+}
+
+// CommonDaysOff returns the days from from to to, inclusive, when at least
+// minOff of the scheduled controllers are off, or all of them when minOff
+// is 0.
+// Days off follow the same rotation as the calendars.
+func (s *Service) CommonDaysOff(ctx context.Context, schedules []models.ControllerSchedule, from, to time.Time, minOff int) []CommonDay {
+	_, done := s.step(ctx, "common_days_off",
+		attribute.Int("calendar.controllers", len(schedules)),
+		attribute.Int("calendar.min", minOff),
+	)
+	defer done()
+
+	if minOff <= 0 || minOff > len(schedules) {
+		minOff = len(schedules)
+	}
+
+	rotations := make([]rotation, 0, len(schedules))
+	ids := make([]int, 0, len(schedules))
+	for _, cs := range schedules {
+		// Without a pair a controller is never off
+		if rot, ok := newRotation(cs.Schedule); ok {
+			rotations = append(rotations, rot)
+			ids = append(ids, cs.Controller.ID)
+		}
+	}
+
+	first := dayNumber(from.Year(), from.Month(), from.Day())
+	last := dayNumber(to.Year(), to.Month(), to.Day())
+
+	days := []CommonDay{}
+	for day := first; day <= last; day++ {
+		off := []int{}
+		protected := []int{}
+		for i, rot := range rotations {
+			hasPair, isProtected := rot.state(day)
+			if !hasPair {
+				continue
+			}
+			off = append(off, ids[i])
+			if isProtected {
+				protected = append(protected, ids[i])
+			}
+		}
+
+		if len(off) == 0 || len(off) < minOff {
+			continue
+		}
+
+		days = append(days, CommonDay{
+			Date:            time.Unix(day*86400, 0).UTC(),
+			Off:             off,
+			Protected:       protected,
+			ProtectedForAll: len(protected) == len(schedules),
+		})
+	}
+
+	return days
+}
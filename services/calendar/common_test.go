@@ -0,0 +1,121 @@
+package calendar
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dukerupert/weekend-warrior/db/models"
+)
+
+// january returns a day of January 2025
+func january(day int) time.Time {
+	return time.Date(2025, time.January, day, 0, 0, 0, 0, time.UTC)
+}
+
+func controllerSchedule(id int, anchor time.Time, rdos ...int) models.ControllerSchedule {
+	return models.ControllerSchedule{
+		Controller: models.Controller{ID: id},
+		Schedule:   models.Schedule{RDOs: rdos, Anchor: anchor},
+	}
+}
+
+func TestCommonDaysOff(t *testing.T) {
+	s := &Service{}
+
+	// Saturday and Sunday pairs from January 11, protected that weekend
+	weekends := controllerSchedule(1, january(6), 6, 0)
+	// The same weekends from a 2022 anchor, 156 weeks before, so also
+	// protected on January 11
+	anchoredLongAgo := controllerSchedule(2, time.Date(2022, time.January, 10, 0, 0, 0, 0, time.UTC), 6, 0)
+	// Sunday and Monday pairs from January 12, protected that week
+	sundays := controllerSchedule(3, january(6), 0, 1)
+	// One RDO is not a pair, so never off
+	noPair := controllerSchedule(4, january(6), 6)
+
+	everyone := []models.ControllerSchedule{weekends, anchoredLongAgo, sundays}
+	allProtected := CommonDay{Date: january(12), Off: []int{1, 2, 3}, Protected: []int{1, 2, 3}, ProtectedForAll: true}
+	allWorking := CommonDay{Date: january(19), Off: []int{1, 2, 3}, Protected: []int{}}
+
+	tests := []struct {
+		name      string
+		schedules []models.ControllerSchedule
+		minOff    int
+		want      []CommonDay
+	}{
+		{
+			name:      "everyone off",
+			schedules: everyone,
+			minOff:    0,
+			want:      []CommonDay{allProtected, allWorking},
+		},
+		{
+			name:      "more than the group means everyone",
+			schedules: everyone,
+			minOff:    4,
+			want:      []CommonDay{allProtected, allWorking},
+		},
+		{
+			name:      "two of three",
+			schedules: everyone,
+			minOff:    2,
+			want: []CommonDay{
+				{Date: january(11), Off: []int{1, 2}, Protected: []int{1, 2}},
+				allProtected,
+				{Date: january(18), Off: []int{1, 2}, Protected: []int{}},
+				allWorking,
+			},
+		},
+		{
+			name:      "one of three",
+			schedules: everyone,
+			minOff:    1,
+			want: []CommonDay{
+				{Date: january(11), Off: []int{1, 2}, Protected: []int{1, 2}},
+				allProtected,
+				{Date: january(13), Off: []int{3}, Protected: []int{3}},
+				{Date: january(18), Off: []int{1, 2}, Protected: []int{}},
+				allWorking,
+				{Date: january(20), Off: []int{3}, Protected: []int{}},
+			},
+		},
+		{
+			name:      "anchored years before the range",
+			schedules: []models.ControllerSchedule{anchoredLongAgo},
+			minOff:    0,
+			want: []CommonDay{
+				{Date: january(11), Off: []int{2}, Protected: []int{2}, ProtectedForAll: true},
+				{Date: january(12), Off: []int{2}, Protected: []int{2}, ProtectedForAll: true},
+				{Date: january(18), Off: []int{2}, Protected: []int{}},
+				{Date: january(19), Off: []int{2}, Protected: []int{}},
+			},
+		},
+		{
+			name:      "a controller without a pair is never off",
+			schedules: []models.ControllerSchedule{weekends, noPair},
+			minOff:    0,
+			want:      []CommonDay{},
+		},
+		{
+			name:      "nor lets the others be protected for all",
+			schedules: []models.ControllerSchedule{weekends, noPair},
+			minOff:    1,
+			want: []CommonDay{
+				{Date: january(11), Off: []int{1}, Protected: []int{1}},
+				{Date: january(12), Off: []int{1}, Protected: []int{1}},
+				{Date: january(18), Off: []int{1}, Protected: []int{}},
+				{Date: january(19), Off: []int{1}, Protected: []int{}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.CommonDaysOff(context.Background(), tt.schedules, january(10), january(20), tt.minOff)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CommonDaysOff() =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
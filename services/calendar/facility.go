@@ -0,0 +1,75 @@
+// services/calendar/facility.go
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dukerupert/weekend-warrior/db/models"
+	"github.com/rs/zerolog"
+)
+
+// FacilityMonth returns the month calendar of every active controller at a
+// facility who has a schedule, ordered by name. Results are cached until
+// the facility's schedules change.
+func (s *Service) FacilityMonth(ctx context.Context, facilityID, year, month int) ([]Calendar, error) {
+	key := monthKey(facilityID, year, month, s.cache.Generation(ctx, facilityID))
+
+	if calendars, ok := s.cache.Get(ctx, key); ok {
+		markToday(calendars, time.Now())
+		return calendars, nil
+	}
+
+	calendars, err := s.generateFacilityMonth(ctx, facilityID, year, month)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.Set(ctx, key, calendars)
+	return calendars, nil
+}
+
+// generateFacilityMonth builds a facility month from the database
+func (s *Service) generateFacilityMonth(ctx context.Context, facilityID, year, month int) ([]Calendar, error) {
+	schedules, err := s.db.GetFacilitySchedules(ctx, facilityID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading facility schedules: %w", err)
+	}
+
+	return s.GenerateFacilityCalendars(ctx, year, month, schedules), nil
+}
+
+// schedulePair returns the weekday pair a schedule rotates on, taken from
+// its first two RDOs
+func schedulePair(schedule *models.Schedule) (time.Weekday, time.Weekday, bool) {
+	if len(schedule.RDOs) < 2 {
+		return 0, 0, false
+	}
+	return time.Weekday(schedule.RDOs[0]), time.Weekday(schedule.RDOs[1]), true
+}
+
+// InvalidateFacility drops every cached month of a facility. Call it after
+// any change to the facility's controllers or schedules.
+func (s *Service) InvalidateFacility(ctx context.Context, facilityID int) {
+	if err := s.cache.Bump(ctx, facilityID); err != nil {
+		zerolog.Ctx(ctx).Warn().
+			Err(err).
+			Int("facility_id", facilityID).
+			Msg("failed to invalidate cached calendars")
+	}
+}
+
+// InvalidateController drops the cached months of the controller's facility
+func (s *Service) InvalidateController(ctx context.Context, controllerID int) {
+	controller, err := s.db.GetControllerByID(ctx, controllerID)
+	if err != nil {
+		zerolog.Ctx(ctx).Warn().
+			Err(err).
+			Int("controller_id", controllerID).
+			Msg("failed to find controller to invalidate cached calendars")
+		return
+	}
+
+	s.InvalidateFacility(ctx, controller.FacilityID)
+}
@@ -0,0 +1,114 @@
+// services/calendar/lru.go
+package calendar
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// LRUCache keeps facility months in process memory, evicting the least
+// recently used month once size entries are stored. It is the fallback
+// when Redis is not configured.
+type LRUCache struct {
+	mu          sync.Mutex
+	size        int
+	ttl         time.Duration
+	order       *list.List
+	entries     map[string]*list.Element
+	generations map[int]int64
+}
+
+type lruEntry struct {
+	key       string
+	calendars []Calendar
+	expires   time.Time
+}
+
+// NewLRUCache creates an in-process cache holding at most size months for
+// at most ttl each
+func NewLRUCache(size int, ttl time.Duration) *LRUCache {
+	return &LRUCache{
+		size:        size,
+		ttl:         ttl,
+		order:       list.New(),
+		entries:     make(map[string]*list.Element),
+		generations: make(map[int]int64),
+	}
+}
+
+// Get implements Cache
+func (l *LRUCache) Get(_ context.Context, key string) ([]Calendar, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	elem, ok := l.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*lruEntry)
+	if time.Now().After(entry.expires) {
+		l.order.Remove(elem)
+		delete(l.entries, key)
+		return nil, false
+	}
+
+	l.order.MoveToFront(elem)
+	return cloneCalendars(entry.calendars), true
+}
+
+// Set implements Cache
+func (l *LRUCache) Set(_ context.Context, key string, calendars []Calendar) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry := &lruEntry{
+		key:       key,
+		calendars: cloneCalendars(calendars),
+		expires:   time.Now().Add(l.ttl),
+	}
+
+	if elem, ok := l.entries[key]; ok {
+		elem.Value = entry
+		l.order.MoveToFront(elem)
+		return
+	}
+
+	l.entries[key] = l.order.PushFront(entry)
+	for l.order.Len() > l.size {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// Generation implements Cache
+func (l *LRUCache) Generation(_ context.Context, facilityID int) int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.generations[facilityID]
+}
+
+// Bump implements Cache
+func (l *LRUCache) Bump(_ context.Context, facilityID int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.generations[facilityID]++
+	return nil
+}
+
+// cloneCalendars copies the day grids so callers can adjust flags such as
+// IsToday without touching the cached copy
+func cloneCalendars(calendars []Calendar) []Calendar {
+	clone := make([]Calendar, len(calendars))
+	for i, cal := range calendars {
+		clone[i] = cal
+		clone[i].Days = make([][]CalendarDay, len(cal.Days))
+		for w, week := range cal.Days {
+			clone[i].Days[w] = append([]CalendarDay(nil), week...)
+		}
+	}
+	return clone
+}
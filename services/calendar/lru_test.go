@@ -0,0 +1,95 @@
+package calendar
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// month returns a one-day calendar tagged with controllerID so cached
+// copies can be told apart
+func month(controllerID int) []Calendar {
+	return []Calendar{{
+		Year:         2025,
+		Month:        int(time.July),
+		Days:         [][]CalendarDay{{{Day: 1, HasPair: true}}},
+		ControllerID: controllerID,
+	}}
+}
+
+func TestLRUCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	cache := NewLRUCache(2, time.Hour)
+
+	cache.Set(ctx, "a", month(1))
+	cache.Set(ctx, "b", month(2))
+
+	// Reading a makes b the least recently used
+	if _, ok := cache.Get(ctx, "a"); !ok {
+		t.Fatal("a missing before the cache was full")
+	}
+	cache.Set(ctx, "c", month(3))
+
+	if _, ok := cache.Get(ctx, "b"); ok {
+		t.Error("b was kept, want it evicted as least recently used")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := cache.Get(ctx, key); !ok {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+}
+
+func TestLRUCacheReplacesExistingKey(t *testing.T) {
+	ctx := context.Background()
+	cache := NewLRUCache(2, time.Hour)
+
+	cache.Set(ctx, "a", month(1))
+	cache.Set(ctx, "a", month(2))
+	cache.Set(ctx, "b", month(3))
+
+	got, ok := cache.Get(ctx, "a")
+	if !ok || got[0].ControllerID != 2 {
+		t.Errorf("Get(a) = %v, %v, want the second value", got, ok)
+	}
+	if _, ok := cache.Get(ctx, "b"); !ok {
+		t.Error("b was evicted, want replacing a to leave room for it")
+	}
+}
+
+func TestLRUCacheExpiresEntries(t *testing.T) {
+	ctx := context.Background()
+	ttl := 20 * time.Millisecond
+	cache := NewLRUCache(2, ttl)
+
+	cache.Set(ctx, "a", month(1))
+	if _, ok := cache.Get(ctx, "a"); !ok {
+		t.Fatal("a missing within its TTL")
+	}
+
+	time.Sleep(ttl + 5*time.Millisecond)
+
+	if _, ok := cache.Get(ctx, "a"); ok {
+		t.Error("a was returned after its TTL")
+	}
+}
+
+func TestLRUCacheReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	cache := NewLRUCache(2, time.Hour)
+
+	stored := month(1)
+	cache.Set(ctx, "a", stored)
+	stored[0].Days[0][0].HasPair = false
+
+	got, _ := cache.Get(ctx, "a")
+	if !got[0].Days[0][0].HasPair {
+		t.Fatal("changing the stored calendars changed the cached copy")
+	}
+
+	got[0].Days[0][0].IsToday = true
+	again, _ := cache.Get(ctx, "a")
+	if again[0].Days[0][0].IsToday {
+		t.Error("changing a returned copy changed the cached copy")
+	}
+}
@@ -0,0 +1,76 @@
+// services/calendar/redis.go
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+)
+
+// RedisCache shares facility months between instances. Redis errors are
+// logged and treated as cache misses so the page still renders.
+type RedisCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewRedisCache creates a cache that keeps months in Redis for ttl
+func NewRedisCache(client *redis.Client, ttl time.Duration) *RedisCache {
+	return &RedisCache{client: client, ttl: ttl}
+}
+
+// generationKey holds a facility's generation counter
+func generationKey(facilityID int) string {
+	return "calendar:generation:" + strconv.Itoa(facilityID)
+}
+
+// Get implements Cache
+func (r *RedisCache) Get(ctx context.Context, key string) ([]Calendar, bool) {
+	data, err := r.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			zerolog.Ctx(ctx).Warn().Err(err).Str("key", key).Msg("calendar cache read failed")
+		}
+		return nil, false
+	}
+
+	var calendars []Calendar
+	if err := json.Unmarshal(data, &calendars); err != nil {
+		zerolog.Ctx(ctx).Warn().Err(err).Str("key", key).Msg("calendar cache entry is corrupt")
+		return nil, false
+	}
+
+	return calendars, true
+}
+
+// Set implements Cache
+func (r *RedisCache) Set(ctx context.Context, key string, calendars []Calendar) {
+	data, err := json.Marshal(calendars)
+	if err != nil {
+		zerolog.Ctx(ctx).Warn().Err(err).Str("key", key).Msg("failed to encode calendars for cache")
+		return
+	}
+
+	if err := r.client.Set(ctx, key, data, r.ttl).Err(); err != nil {
+		zerolog.Ctx(ctx).Warn().Err(err).Str("key", key).Msg("calendar cache write failed")
+	}
+}
+
+// Generation implements Cache
+func (r *RedisCache) Generation(ctx context.Context, facilityID int) int64 {
+	generation, err := r.client.Get(ctx, generationKey(facilityID)).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		zerolog.Ctx(ctx).Warn().Err(err).Int("facility_id", facilityID).Msg("calendar cache generation read failed")
+	}
+	return generation
+}
+
+// Bump implements Cache
+func (r *RedisCache) Bump(ctx context.Context, facilityID int) error {
+	return r.client.Incr(ctx, generationKey(facilityID)).Err()
+}
@@ -0,0 +1,124 @@
+// services/calendar/swap.go
+package calendar
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/dukerupert/weekend-warrior/db/models"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// SwapDay is one of the requester's days off a colleague could take in
+// return for working the target date
+type SwapDay struct {
+	Date time.Time `json:"date"`
+	// Distance is how many days the date is from the target, either way
+	Distance int `json:"distance"`
+}
+
+// SwapCandidate is a colleague who could trade days off with the requester
+type SwapCandidate struct {
+	Controller models.Controller `json:"controller"`
+	// Days lists the dates they could take off instead, closest first
+	Days []SwapDay `json:"days"`
+}
+
+// SwapCandidates finds the colleagues who could work target for the
+// requester in return for one of the requester's days off from from to to,
+// inclusive. A candidate is off on target and working on the day they
+// would take. Days on a protected pair are never traded, on either side,
+// and a day is only traded when at least minOnDuty controllers of the
+// facility are working it. Candidates are ranked by their closest day, then
+// by the order given.
+//
+// The requester must be working target; schedules is every controller at
+// the facility and may include the requester.
+func (s *Service) SwapCandidates(ctx context.Context, requester models.ControllerSchedule, schedules []models.ControllerSchedule, target, from, to time.Time, minOnDuty int) []SwapCandidate {
+	_, done := s.step(ctx, "swap_candidates",
+		attribute.Int("calendar.controllers", len(schedules)),
+		attribute.Int("calendar.min_on_duty", minOnDuty),
+	)
+	defer done()
+
+	candidates := []SwapCandidate{}
+
+	own, ok := newRotation(requester.Schedule)
+	if !ok {
+		// Without a pair the requester has no day off to give
+		return candidates
+	}
+
+	// A trade moves one controller each way, so the number working a day is
+	// the same before and after it
+	rotations := make([]rotation, len(schedules))
+	hasRotation := make([]bool, len(schedules))
+	for i, cs := range schedules {
+		rotations[i], hasRotation[i] = newRotation(cs.Schedule)
+	}
+	onDuty := func(day int64) int {
+		working := len(schedules)
+		for i, rot := range rotations {
+			if hasRotation[i] {
+				if off, _ := rot.state(day); off {
+					working--
+				}
+			}
+		}
+		return working
+	}
+
+	targetDay := dayNumber(target.Year(), target.Month(), target.Day())
+	if off, _ := own.state(targetDay); off || onDuty(targetDay) < minOnDuty {
+		return candidates
+	}
+
+	// The requester's days off they are free to give away
+	var offered []int64
+	first := dayNumber(from.Year(), from.Month(), from.Day())
+	last := dayNumber(to.Year(), to.Month(), to.Day())
+	for day := first; day <= last; day++ {
+		if off, protected := own.state(day); off && !protected && onDuty(day) >= minOnDuty {
+			offered = append(offered, day)
+		}
+	}
+
+	for i, cs := range schedules {
+		if cs.Controller.ID == requester.Controller.ID || !hasRotation[i] {
+			continue
+		}
+		if off, protected := rotations[i].state(targetDay); !off || protected {
+			continue
+		}
+
+		days := []SwapDay{}
+		for _, day := range offered {
+			if off, _ := rotations[i].state(day); off {
+				continue
+			}
+			distance := day - targetDay
+			if distance < 0 {
+				distance = -distance
+			}
+			days = append(days, SwapDay{
+				Date:     time.Unix(day*86400, 0).UTC(),
+				Distance: int(distance),
+			})
+		}
+		if len(days) == 0 {
+			continue
+		}
+
+		sort.SliceStable(days, func(a, b int) bool {
+			return days[a].Distance < days[b].Distance
+		})
+		candidates = append(candidates, SwapCandidate{Controller: cs.Controller, Days: days})
+	}
+
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return candidates[a].Days[0].Distance < candidates[b].Days[0].Distance
+	})
+
+	return candidates
+}
@@ -0,0 +1,116 @@
+package calendar
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dukerupert/weekend-warrior/db/models"
+)
+
+// swapDay is a day of January 2025 and its distance from the target
+func swapDay(day, distance int) SwapDay {
+	return SwapDay{Date: january(day), Distance: distance}
+}
+
+func TestSwapCandidates(t *testing.T) {
+	s := &Service{}
+	longAgo := time.Date(2022, time.January, 10, 0, 0, 0, 0, time.UTC)
+
+	// Off on the weekends of January 11 (protected), 18 and 25, from an
+	// anchor three years back
+	requester := controllerSchedule(1, longAgo, 6, 0)
+	// Off Wednesday the 15th and Saturday the 18th, and the 22nd and 25th
+	wednesdaySaturday := controllerSchedule(2, january(6), 3, 6)
+	// Off Tuesday the 14th and Wednesday the 15th, and the 21st and 22nd
+	tuesdayWednesday := controllerSchedule(3, longAgo, 2, 3)
+	// Off the 15th too, but on a protected pair
+	protectedWednesday := controllerSchedule(4, time.Date(2024, time.December, 23, 0, 0, 0, 0, time.UTC), 3, 4)
+	// Off Fridays and Saturdays, so working the 15th
+	fridaySaturday := controllerSchedule(5, january(6), 5, 6)
+	alsoFridaySaturday := controllerSchedule(6, january(6), 5, 6)
+	// Never off
+	noPair := controllerSchedule(7, january(6), 1)
+
+	// Seven controllers, four of them working the 15th and three the
+	// Saturdays, six the Sundays
+	facility := []models.ControllerSchedule{
+		requester, wednesdaySaturday, tuesdayWednesday, protectedWednesday,
+		fridaySaturday, alsoFridaySaturday, noPair,
+	}
+
+	tests := []struct {
+		name      string
+		requester models.ControllerSchedule
+		target    time.Time
+		minOnDuty int
+		want      []SwapCandidate
+	}{
+		{
+			// The protected weekend of the 11th is not offered, and the
+			// closest day ranks tuesdayWednesday first though it is given
+			// second
+			name:      "ranked by the closest day",
+			requester: requester,
+			target:    january(15),
+			minOnDuty: 0,
+			want: []SwapCandidate{
+				{
+					Controller: tuesdayWednesday.Controller,
+					Days:       []SwapDay{swapDay(18, 3), swapDay(19, 4), swapDay(25, 10), swapDay(26, 11)},
+				},
+				{
+					Controller: wednesdaySaturday.Controller,
+					Days:       []SwapDay{swapDay(19, 4), swapDay(26, 11)},
+				},
+			},
+		},
+		{
+			name:      "days below the minimum on duty are not traded",
+			requester: requester,
+			target:    january(15),
+			minOnDuty: 4,
+			want: []SwapCandidate{
+				{
+					Controller: wednesdaySaturday.Controller,
+					Days:       []SwapDay{swapDay(19, 4), swapDay(26, 11)},
+				},
+				{
+					Controller: tuesdayWednesday.Controller,
+					Days:       []SwapDay{swapDay(19, 4), swapDay(26, 11)},
+				},
+			},
+		},
+		{
+			name:      "nor is a target below the minimum",
+			requester: requester,
+			target:    january(15),
+			minOnDuty: 5,
+			want:      []SwapCandidate{},
+		},
+		{
+			name:      "the requester is already off",
+			requester: requester,
+			target:    january(19),
+			minOnDuty: 0,
+			want:      []SwapCandidate{},
+		},
+		{
+			name:      "the requester has no days off to give",
+			requester: noPair,
+			target:    january(15),
+			minOnDuty: 0,
+			want:      []SwapCandidate{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.SwapCandidates(context.Background(), tt.requester, facility, tt.target, january(10), january(26), tt.minOnDuty)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SwapCandidates() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
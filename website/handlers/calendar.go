@@ -1,40 +1,61 @@
 package handlers
 
 import (
+	"fmt"
+	"html/template"
 	"strconv"
 	"time"
 
+	"github.com/dukerupert/weekend-warrior/db"
+	"github.com/dukerupert/weekend-warrior/db/models"
+	"github.com/dukerupert/weekend-warrior/middleware"
 	"github.com/dukerupert/weekend-warrior/services/calendar"
 	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
 )
 
 type CalendarHandler struct {
 	calendarService *calendar.Service
+	dbService       *db.Service
 }
 
-func NewCalendarHandler(calendarService *calendar.Service) *CalendarHandler {
+func NewCalendarHandler(calendarService *calendar.Service, dbService *db.Service) *CalendarHandler {
 	return &CalendarHandler{
 		calendarService: calendarService,
+		dbService:       dbService,
 	}
 }
 
 type TemplateData struct {
+	// Month is the bare grid the controller calendars are drawn over
+	Month     calendar.Calendar
+	Facility  *models.Facility
 	Calendars []calendar.Calendar
+	// PrevURL and NextURL load the neighbouring months, keeping the facility
+	PrevURL string
+	NextURL string
 }
 
-// Example handler showing how to access DB from context
-func (h *CalendarHandler) CalendarHandler(c *fiber.Ctx) error {
-	// You can access the DB connection from context if needed
-	// db := c.Locals("db").(*pgx.Conn)
+// Years the calendar pages accept; others fall back to the current year
+const (
+	minYear = 1
+	maxYear = 9999
+)
 
-	// Use db for queries...
+// CalendarHandler renders a facility month with every scheduled
+// controller's pairs. The facility comes from the facility query parameter
+// (a facility code) or else the signed-in controller's facility. Requests
+// from htmx get only the month fragment.
+func (h *CalendarHandler) CalendarHandler(c *fiber.Ctx) error {
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "CalendarHandler").
+		Logger()
 
 	// Handle url query values
 	year, month := h.calendarService.GetCurrentYearMonth()
-	if yearStr := c.Query("year"); yearStr != "" {
-		if y, err := strconv.Atoi(yearStr); err == nil {
-			year = y
-		}
+	if y, err := strconv.Atoi(c.Query("year")); err == nil && y >= minYear && y <= maxYear {
+		year = y
 	}
 
 	if monthStr := c.Query("month"); monthStr != "" {
@@ -43,23 +64,206 @@ func (h *CalendarHandler) CalendarHandler(c *fiber.Ctx) error {
 		}
 	}
 
-	// Generate multiple calendars
-	var calendars []calendar.Calendar
+	prev := time.Date(year, time.Month(month)-1, 1, 0, 0, 0, 0, time.UTC)
+	next := time.Date(year, time.Month(month)+1, 1, 0, 0, 0, 0, time.UTC)
+
+	data := TemplateData{
+		Month:   h.calendarService.GenerateCalendar(c.UserContext(), year, month, nil, "", 0),
+		PrevURL: monthURL(c, prev.Year(), int(prev.Month())),
+		NextURL: monthURL(c, next.Year(), int(next.Month())),
+	}
+
+	facility, err := h.resolveFacility(c)
+	if err != nil {
+		if isNotFoundError(err) {
+			return fiber.NewError(fiber.StatusNotFound, "No facility found with code "+c.Query("facility"))
+		}
+
+		reqLogger.Error().Err(err).Msg("failed to resolve facility")
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to load calendar")
+	}
+
+	if facility != nil {
+		calendars, err := h.calendarService.FacilityMonth(c.UserContext(), facility.ID, year, month)
+		if err != nil {
+			reqLogger.Error().
+				Err(err).
+				Int("facility_id", facility.ID).
+				Msg("failed to generate facility calendars")
 
-	anchorDate1 := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
-	anchorDate2 := time.Date(year, time.Month(month), 7, 0, 0, 0, 0, time.UTC)
-	// Example: Generate calendars for different pairs
-	pairs1 := h.calendarService.GenerateWeekdayPairs(time.Saturday, time.Sunday, anchorDate1)
-	cal1 := h.calendarService.GenerateCalendar(year, month, pairs1, "YC", 0)
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to load calendar")
+		}
 
-	pairs2 := h.calendarService.GenerateWeekdayPairs(time.Sunday, time.Monday, anchorDate2)
-	cal2 := h.calendarService.GenerateCalendar(year, month, pairs2, "AB", 1)
+		data.Facility = facility
+		data.Calendars = calendars
+	}
 
-	calendars = append(calendars, cal1, cal2)
+	if isPartial(c) {
+		return renderPartial(c, fiber.StatusOK, "calendar_month", data)
+	}
 
-	data := TemplateData{
-		Calendars: calendars,
+	title := "Calendar"
+	if facility != nil {
+		title = facility.Code + " Calendar"
+	}
+
+	c.Vary(headerHXRequest)
+	return c.Render("calendar", fiber.Map{
+		"Title":    title,
+		"Calendar": data,
+	})
+}
+
+// YearData is the view model of the year at a glance page
+type YearData struct {
+	Year     int
+	Facility *models.Facility
+	// Months pairs the bare grid of each month with the selected
+	// controllers' calendars for it
+	Months []YearMonth
+	// Controllers lists everyone at the facility with a schedule, for the
+	// picker and the summary
+	Controllers []YearController
+	// PrevURL and NextURL load the neighbouring years, keeping the selection
+	PrevURL string
+	NextURL string
+}
+
+// YearMonth is one month of the year at a glance page
+type YearMonth struct {
+	Month     calendar.Calendar
+	Calendars []calendar.Calendar
+}
+
+// YearController is a controller offered on the year at a glance page
+type YearController struct {
+	models.Controller
+	Selected bool
+	// Color matches the controller's marks in the months
+	Color template.CSS
+	// ProtectedPairs counts the protected weekends starting in the year
+	ProtectedPairs int
+}
+
+// YearHandler renders twelve months of a facility's calendar for one or more
+// of its controllers, marking their days off and protected pairs, with each
+// controller's count of protected weekends for the year. The facility is
+// resolved as for CalendarHandler; controllers are picked with repeated
+// controller query parameters.
+func (h *CalendarHandler) YearHandler(c *fiber.Ctx) error {
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "YearHandler").
+		Logger()
+
+	year, _ := h.calendarService.GetCurrentYearMonth()
+	if y, err := strconv.Atoi(c.Query("year")); err == nil && y >= minYear && y <= maxYear {
+		year = y
+	}
+
+	data := YearData{
+		Year:    year,
+		PrevURL: yearURL(c, year-1),
+		NextURL: yearURL(c, year+1),
+	}
+
+	facility, err := h.resolveFacility(c)
+	if err != nil {
+		if isNotFoundError(err) {
+			return fiber.NewError(fiber.StatusNotFound, "No facility found with code "+c.Query("facility"))
+		}
+
+		reqLogger.Error().Err(err).Msg("failed to resolve facility")
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to load calendar")
+	}
+
+	if facility != nil {
+		schedules, err := h.dbService.GetFacilitySchedules(c.UserContext(), facility.ID)
+		if err != nil {
+			reqLogger.Error().
+				Err(err).
+				Int("facility_id", facility.ID).
+				Msg("failed to load facility schedules")
+
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to load calendar")
+		}
+
+		selected := selectedSchedules(c, schedules)
+		months := h.calendarService.GenerateMonths(c.UserContext(), year, 1, 12, selected)
+		for i, calendars := range months {
+			data.Months = append(data.Months, YearMonth{
+				Month:     h.calendarService.GenerateCalendar(c.UserContext(), year, i+1, nil, "", 0),
+				Calendars: calendars,
+			})
+		}
+
+		colors := map[int]template.CSS{}
+		for _, cal := range months[0] {
+			colors[cal.ControllerID] = cal.Color
+		}
+
+		for _, cs := range schedules {
+			color, ok := colors[cs.Controller.ID]
+			data.Controllers = append(data.Controllers, YearController{
+				Controller:     cs.Controller,
+				Selected:       ok,
+				Color:          color,
+				ProtectedPairs: h.calendarService.ProtectedPairs(cs.Schedule, year),
+			})
+		}
+
+		data.Facility = facility
+	}
+
+	reqLogger.Debug().
+		Int("year", year).
+		Int("controller_count", len(data.Controllers)).
+		Msg("rendering year calendar")
+
+	return c.Render("calendar_year", fiber.Map{
+		"Title": fmt.Sprintf("%d at a Glance", year),
+		"Year":  data,
+	})
+}
+
+// selectedSchedules picks the schedules of the controllers named by
+// controller query parameters. Without any, the signed-in controller is
+// picked, or everyone when they have no schedule among these.
+func selectedSchedules(c *fiber.Ctx, schedules []models.ControllerSchedule) []models.ControllerSchedule {
+	ids := map[int]bool{}
+	for _, raw := range c.Request().URI().QueryArgs().PeekMulti("controller") {
+		if id, err := strconv.Atoi(string(raw)); err == nil {
+			ids[id] = true
+		}
+	}
+	if len(ids) == 0 {
+		if user := middleware.CurrentUser(c); user != nil {
+			ids[user.ID] = true
+		}
+	}
+
+	var selected []models.ControllerSchedule
+	for _, cs := range schedules {
+		if ids[cs.Controller.ID] {
+			selected = append(selected, cs)
+		}
+	}
+	if selected == nil {
+		return schedules
+	}
+	return selected
+}
+
+// resolveFacility returns the facility named in the query, the signed-in
+// controller's facility, or nil when neither is available
+func (h *CalendarHandler) resolveFacility(c *fiber.Ctx) (*models.Facility, error) {
+	if code := c.Query("facility"); code != "" {
+		return h.dbService.GetFacilityByCode(c.UserContext(), code)
+	}
+
+	if user := middleware.CurrentUser(c); user != nil {
+		return h.dbService.GetFacilityByID(c.UserContext(), user.FacilityID)
 	}
 
-	return c.Render("calendar", data)
+	return nil, nil
 }
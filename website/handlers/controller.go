@@ -2,39 +2,48 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/dukerupert/weekend-warrior/db"
 	"github.com/dukerupert/weekend-warrior/db/models"
+	"github.com/dukerupert/weekend-warrior/middleware"
+	"github.com/dukerupert/weekend-warrior/pkg/validation"
+	"github.com/dukerupert/weekend-warrior/services/calendar"
 	"github.com/gofiber/fiber/v2"
 	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
 )
 
 type ControllerHandler struct {
-	dbService *db.Service
-	logger    zerolog.Logger
+	dbService       *db.Service
+	calendarService *calendar.Service
 }
 
-func NewControllerHandler(dbService *db.Service) *ControllerHandler {
+func NewControllerHandler(dbService *db.Service, calendarService *calendar.Service) *ControllerHandler {
 	return &ControllerHandler{
-		dbService: dbService,
-		logger:    log.With().Str("handler", "controller").Logger(),
+		dbService:       dbService,
+		calendarService: calendarService,
 	}
 }
 
 // ListControllers handles GET requests to list all controllers
 func (h *ControllerHandler) ListControllers(c *fiber.Ctx) error {
 	// Create request-specific logger
-	reqLogger := h.logger.With().
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
 		Str("method", "ListControllers").
-		Str("request_id", c.GetRespHeader("X-Request-ID")).
 		Logger()
 
-	reqLogger.Info().Msg("retrieving controllers list")
+	params := models.ListControllersParams{
+		IncludeArchived: c.QueryBool("include_archived"),
+	}
+
+	reqLogger.Info().
+		Bool("include_archived", params.IncludeArchived).
+		Msg("retrieving controllers list")
 
-	controllers, err := h.dbService.ListControllers(c.Context())
+	controllers, err := h.dbService.ListControllers(c.UserContext(), params)
 	if err != nil {
 		reqLogger.Error().
 			Err(err).
@@ -55,91 +64,105 @@ func (h *ControllerHandler) ListControllers(c *fiber.Ctx) error {
 	})
 }
 
-// CreateController handles POST requests to create a new controller
-func (h *ControllerHandler) CreateController(c *fiber.Ctx) error {
+// GetController handles GET requests to retrieve a controller by ID
+func (h *ControllerHandler) GetController(c *fiber.Ctx) error {
 	// Create request-specific logger
-	reqLogger := h.logger.With().
-		Str("method", "CreateController").
-		Str("request_id", c.GetRespHeader("X-Request-ID")).
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "GetController").
 		Logger()
 
-	reqLogger.Info().Msg("processing create controller request")
+	reqLogger.Info().Msg("processing get controller request")
 
-	var params models.CreateControllerParams
-	if err := c.BodyParser(&params); err != nil {
+	// Parse and validate ID
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
 		reqLogger.Error().
 			Err(err).
-			Str("body", string(c.Body())).
-			Msg("failed to parse request body")
+			Str("id_raw", c.Params("id")).
+			Msg("invalid controller ID format")
 
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":  "Invalid request body",
-			"detail": err.Error(),
+			"error":  "Invalid controller ID",
+			"detail": "ID must be a number",
 		})
 	}
 
-	// Validation logging with detailed context
-	if params.Name == "" {
-		reqLogger.Error().
-			Interface("params", params).
-			Msg("validation failed: name is required")
+	controller, err := h.dbService.GetControllerByID(c.UserContext(), id)
+	if err != nil {
+		if isNotFoundError(err) {
+			reqLogger.Warn().
+				Int("controller_id", id).
+				Msg("controller not found")
 
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":  "Invalid request",
-			"detail": "name is required",
-		})
-	}
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":  "Controller not found",
+				"detail": fmt.Sprintf("no controller found with ID %d", id),
+			})
+		}
 
-	if len(params.Initials) != 2 {
 		reqLogger.Error().
-			Str("initials", params.Initials).
-			Interface("params", params).
-			Msg("validation failed: invalid initials length")
+			Err(err).
+			Int("controller_id", id).
+			Msg("failed to retrieve controller")
 
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":  "Invalid request",
-			"detail": "initials must be exactly 2 characters",
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to retrieve controller",
+			"detail": err.Error(),
 		})
 	}
 
-	if params.Email == "" {
+	reqLogger.Info().
+		Int("controller_id", controller.ID).
+		Int("version", controller.Version).
+		Msg("controller retrieved successfully")
+
+	setETag(c, controller.Version)
+	return c.JSON(fiber.Map{
+		"data": controller,
+	})
+}
+
+// CreateController handles POST requests to create a new controller
+func (h *ControllerHandler) CreateController(c *fiber.Ctx) error {
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "CreateController").
+		Logger()
+
+	reqLogger.Info().Msg("processing create controller request")
+
+	var params models.CreateControllerParams
+	if err := c.BodyParser(&params); err != nil {
 		reqLogger.Error().
-			Interface("params", params).
-			Msg("validation failed: email is required")
+			Err(err).
+			Msg("failed to parse request body")
 
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":  "Invalid request",
-			"detail": "email is required",
+			"error":  "Invalid request body",
+			"detail": err.Error(),
 		})
 	}
 
-	if params.FacilityID <= 0 {
-		reqLogger.Error().
-			Int("facility_id", params.FacilityID).
-			Interface("params", params).
-			Msg("validation failed: invalid facility ID")
+	params.Initials = strings.ToUpper(params.Initials)
 
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":  "Invalid request",
-			"detail": "facility_id must be a positive number",
-		})
+	if err := validation.Struct(params); err != nil {
+		reqLogger.Warn().
+			Err(err).
+			Msg("validation failed")
+
+		return validationFailed(c, err)
 	}
 
 	// Log validated parameters before database operation
 	reqLogger.Debug().
-		Str("name", params.Name).
-		Str("initials", params.Initials).
-		Str("email", params.Email).
 		Int("facility_id", params.FacilityID).
 		Msg("attempting to create controller")
 
-	controller, err := h.dbService.CreateController(c.Context(), params)
+	controller, err := h.dbService.CreateController(c.UserContext(), params)
 	if err != nil {
 		if isDuplicateKeyError(err) {
 			reqLogger.Warn().
 				Err(err).
-				Str("email", params.Email).
-				Str("initials", params.Initials).
 				Int("facility_id", params.FacilityID).
 				Msg("duplicate controller detected")
 
@@ -151,7 +174,7 @@ func (h *ControllerHandler) CreateController(c *fiber.Ctx) error {
 
 		reqLogger.Error().
 			Err(err).
-			Interface("params", params).
+			Int("facility_id", params.FacilityID).
 			Msg("failed to create controller")
 
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -162,11 +185,10 @@ func (h *ControllerHandler) CreateController(c *fiber.Ctx) error {
 
 	reqLogger.Info().
 		Int("controller_id", controller.ID).
-		Str("name", controller.Name).
-		Str("email", controller.Email).
 		Int("facility_id", controller.FacilityID).
 		Msg("controller created successfully")
 
+	setETag(c, controller.Version)
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"data": controller,
 	})
@@ -175,9 +197,8 @@ func (h *ControllerHandler) CreateController(c *fiber.Ctx) error {
 // UpdateController handles PUT requests to update a controller
 func (h *ControllerHandler) UpdateController(c *fiber.Ctx) error {
 	// Create request-specific logger
-	reqLogger := h.logger.With().
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
 		Str("method", "UpdateController").
-		Str("request_id", c.GetRespHeader("X-Request-ID")).
 		Logger()
 
 	reqLogger.Info().Msg("processing update controller request")
@@ -196,12 +217,22 @@ func (h *ControllerHandler) UpdateController(c *fiber.Ctx) error {
 		})
 	}
 
+	// The client must name the version it last read
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		reqLogger.Warn().
+			Err(err).
+			Int("controller_id", id).
+			Msg("update rejected without a usable If-Match header")
+
+		return preconditionError(c, err)
+	}
+
 	// Parse request body
 	var params models.CreateControllerParams
 	if err := c.BodyParser(&params); err != nil {
 		reqLogger.Error().
 			Err(err).
-			Str("body", string(c.Body())).
 			Msg("failed to parse request body")
 
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -210,24 +241,77 @@ func (h *ControllerHandler) UpdateController(c *fiber.Ctx) error {
 		})
 	}
 
+	params.Initials = strings.ToUpper(params.Initials)
+
+	if err := validation.Struct(params); err != nil {
+		reqLogger.Warn().
+			Err(err).
+			Int("controller_id", id).
+			Msg("validation failed")
+
+		return validationFailed(c, err)
+	}
+
 	// Log update attempt with parameters
 	reqLogger.Debug().
 		Int("controller_id", id).
-		Str("name", params.Name).
-		Str("initials", params.Initials).
-		Str("email", params.Email).
 		Int("facility_id", params.FacilityID).
 		Msg("attempting to update controller")
 
+	// Remember the current facility, a move changes two facility calendars
+	previous, err := h.dbService.GetControllerByID(c.UserContext(), id)
+	if err != nil && !isNotFoundError(err) {
+		reqLogger.Error().
+			Err(err).
+			Int("controller_id", id).
+			Msg("failed to load controller before update")
+
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to update controller",
+			"detail": err.Error(),
+		})
+	}
+
 	// Perform update
-	controller, err := h.dbService.UpdateController(c.Context(), id, params)
+	controller, err := h.dbService.UpdateController(c.UserContext(), id, version, params)
 	if err != nil {
+		if errors.Is(err, db.ErrStaleVersion) {
+			reqLogger.Warn().
+				Int("controller_id", id).
+				Int("version", version).
+				Msg("controller update rejected, version is stale")
+
+			current, getErr := h.dbService.GetControllerByID(c.UserContext(), id)
+			if getErr != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error":  "Failed to retrieve controller",
+					"detail": getErr.Error(),
+				})
+			}
+
+			setETag(c, current.Version)
+			return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+				"error":  "Controller has changed",
+				"detail": "the controller was modified by someone else; review the current version and retry",
+				"data":   current,
+			})
+		}
+
+		if isNotFoundError(err) {
+			reqLogger.Warn().
+				Int("controller_id", id).
+				Msg("controller not found for update")
+
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":  "Controller not found",
+				"detail": fmt.Sprintf("no controller found with ID %d", id),
+			})
+		}
+
 		if isDuplicateKeyError(err) {
 			reqLogger.Warn().
 				Err(err).
 				Int("controller_id", id).
-				Str("email", params.Email).
-				Str("initials", params.Initials).
 				Int("facility_id", params.FacilityID).
 				Msg("duplicate controller detected during update")
 
@@ -240,7 +324,6 @@ func (h *ControllerHandler) UpdateController(c *fiber.Ctx) error {
 		reqLogger.Error().
 			Err(err).
 			Int("controller_id", id).
-			Interface("params", params).
 			Msg("failed to update controller")
 
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -252,22 +335,26 @@ func (h *ControllerHandler) UpdateController(c *fiber.Ctx) error {
 	// Log successful update
 	reqLogger.Info().
 		Int("controller_id", controller.ID).
-		Str("name", controller.Name).
-		Str("email", controller.Email).
 		Int("facility_id", controller.FacilityID).
 		Msg("controller updated successfully")
 
+	h.calendarService.InvalidateFacility(c.UserContext(), controller.FacilityID)
+	if previous != nil && previous.FacilityID != controller.FacilityID {
+		h.calendarService.InvalidateFacility(c.UserContext(), previous.FacilityID)
+	}
+
+	setETag(c, controller.Version)
 	return c.JSON(fiber.Map{
 		"data": controller,
 	})
 }
 
-// DeleteController handles DELETE requests to delete a controller
+// DeleteController handles DELETE requests to archive a controller. The
+// controller's schedule and role history are kept.
 func (h *ControllerHandler) DeleteController(c *fiber.Ctx) error {
 	// Create request-specific logger
-	reqLogger := h.logger.With().
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
 		Str("method", "DeleteController").
-		Str("request_id", c.GetRespHeader("X-Request-ID")).
 		Logger()
 
 	reqLogger.Info().Msg("processing delete controller request")
@@ -288,92 +375,127 @@ func (h *ControllerHandler) DeleteController(c *fiber.Ctx) error {
 
 	reqLogger.Debug().
 		Int("controller_id", id).
-		Msg("attempting to delete controller")
+		Msg("attempting to archive controller")
 
-	err = h.dbService.DeleteController(c.Context(), id)
+	err = h.dbService.ArchiveController(c.UserContext(), id)
 	if err != nil {
 		if isNotFoundError(err) {
 			reqLogger.Warn().
 				Int("controller_id", id).
-				Msg("controller not found for deletion")
+				Msg("controller not found for archiving")
 
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error":  "Controller not found",
-				"detail": fmt.Sprintf("no controller found with ID %d", id),
+				"detail": fmt.Sprintf("no active controller found with ID %d", id),
 			})
 		}
 
 		reqLogger.Error().
 			Err(err).
 			Int("controller_id", id).
-			Msg("failed to delete controller")
+			Msg("failed to archive controller")
 
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":  "Failed to delete controller",
+			"error":  "Failed to archive controller",
 			"detail": err.Error(),
 		})
 	}
 
 	reqLogger.Info().
 		Int("controller_id", id).
-		Msg("controller deleted successfully")
+		Msg("controller archived successfully")
+
+	h.calendarService.InvalidateController(c.UserContext(), id)
 
 	return c.Status(fiber.StatusNoContent).Send(nil)
 }
 
-// ShowCreateForm renders the controller creation form
-func (h *ControllerHandler) ShowCreateForm(c *fiber.Ctx) error {
+// RestoreController handles POST requests to restore an archived controller
+func (h *ControllerHandler) RestoreController(c *fiber.Ctx) error {
 	// Create request-specific logger
-	reqLogger := h.logger.With().
-		Str("method", "ShowCreateForm").
-		Str("request_id", c.GetRespHeader("X-Request-ID")).
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "RestoreController").
 		Logger()
 
-	reqLogger.Info().
-		Str("template", "controllers/manage").
-		Str("title", "Create New Controller").
-		Bool("edit_mode", false).
-		Msg("rendering controller creation form")
-
-	err := c.Render("controllers/manage", fiber.Map{
-		"Title":      "Create New Controller",
-		"EditMode":   false,
-		"Controller": nil,
-	})
+	reqLogger.Info().Msg("processing restore controller request")
+
+	// Parse and validate ID
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		reqLogger.Error().
+			Err(err).
+			Str("id_raw", c.Params("id")).
+			Msg("invalid controller ID format")
+
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Invalid controller ID",
+			"detail": "ID must be a number",
+		})
+	}
+
+	controller, err := h.dbService.RestoreController(c.UserContext(), id)
 	if err != nil {
+		if isNotFoundError(err) {
+			reqLogger.Warn().
+				Int("controller_id", id).
+				Msg("archived controller not found for restore")
+
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":  "Controller not found",
+				"detail": fmt.Sprintf("no archived controller found with ID %d", id),
+			})
+		}
+
+		if isDuplicateKeyError(err) {
+			reqLogger.Warn().
+				Err(err).
+				Int("controller_id", id).
+				Msg("restored controller conflicts with an active controller")
+
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":  "Controller restore conflict",
+				"detail": "Email or initials already in use at this facility",
+			})
+		}
+
 		reqLogger.Error().
 			Err(err).
-			Str("template", "controllers/manage").
-			Msg("failed to render controller creation form")
+			Int("controller_id", id).
+			Msg("failed to restore controller")
 
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":  "Failed to render form",
+			"error":  "Failed to restore controller",
 			"detail": err.Error(),
 		})
 	}
 
-	reqLogger.Debug().Msg("controller creation form rendered successfully")
+	reqLogger.Info().
+		Int("controller_id", controller.ID).
+		Msg("controller restored successfully")
+
+	h.calendarService.InvalidateFacility(c.UserContext(), controller.FacilityID)
 
-	return nil
+	return c.JSON(fiber.Map{
+		"data": controller,
+	})
 }
 
-// ShowEditForm renders the controller edit form with preloaded data
-func (h *ControllerHandler) ShowEditForm(c *fiber.Ctx) error {
+// PurgeController handles DELETE requests to permanently remove an archived
+// controller together with its schedule and role assignments
+func (h *ControllerHandler) PurgeController(c *fiber.Ctx) error {
 	// Create request-specific logger
-	reqLogger := h.logger.With().
-		Str("method", "ShowEditForm").
-		Str("request_id", c.GetRespHeader("X-Request-ID")).
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "PurgeController").
 		Logger()
 
-	reqLogger.Info().Msg("processing edit form request")
+	reqLogger.Info().Msg("processing purge controller request")
 
-	// Validate the ID
-	controllerID := c.Params("id")
-	id, err := strconv.Atoi(controllerID)
+	// Parse and validate ID
+	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
 		reqLogger.Error().
 			Err(err).
-			Str("id_raw", controllerID).
+			Str("id_raw", c.Params("id")).
 			Msg("invalid controller ID format")
 
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -382,164 +504,539 @@ func (h *ControllerHandler) ShowEditForm(c *fiber.Ctx) error {
 		})
 	}
 
-	// Log attempt to fetch controller
-	reqLogger.Debug().
-		Int("controller_id", id).
-		Msg("fetching controller data for edit form")
-
-	// Fetch the controller data
-	controller, err := h.dbService.GetControllerByID(c.Context(), id)
-	if err != nil {
+	if err := h.dbService.PurgeController(c.UserContext(), id); err != nil {
 		if isNotFoundError(err) {
 			reqLogger.Warn().
 				Int("controller_id", id).
-				Msg("controller not found for edit form")
+				Msg("archived controller not found for purge")
 
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error":  "Controller not found",
-				"detail": fmt.Sprintf("no controller found with ID %d", id),
+				"detail": fmt.Sprintf("no archived controller found with ID %d; archive it before purging", id),
 			})
 		}
 
 		reqLogger.Error().
 			Err(err).
 			Int("controller_id", id).
-			Msg("failed to retrieve controller for edit form")
+			Msg("failed to purge controller")
 
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":  "Failed to retrieve controller",
+			"error":  "Failed to purge controller",
 			"detail": err.Error(),
 		})
 	}
 
-	// Log render attempt
-	reqLogger.Debug().
+	reqLogger.Info().
 		Int("controller_id", id).
-		Str("controller_name", controller.Name).
-		Str("template", "controllers/manage").
-		Msg("rendering controller edit form")
-
-	// Render the form
-	err = c.Render("controllers/manage", fiber.Map{
-		"Title":      "Assign Schedule",
-		"Controller": controller,
+		Msg("controller purged successfully")
+
+	return c.Status(fiber.StatusNoContent).Send(nil)
+}
+
+// ControllerRow is a controller list row with its facility code resolved
+type ControllerRow struct {
+	models.Controller
+	FacilityCode string
+}
+
+// ControllerForm holds what the controller form fragment renders
+type ControllerForm struct {
+	// Controller is the controller being edited, nil on the creation form
+	Controller *models.Controller
+	Version    int
+	Values     models.CreateControllerParams
+	Facilities []models.Facility
+	Errors     validation.FieldErrors
+	// Message is a problem with the whole form, Saved a success notice
+	Message string
+	Saved   string
+	// Created is appended to the page's controller list out of band
+	Created *ControllerRow
+	// Redirect is the page to show once the controller is created, for
+	// forms that are not next to a controller list
+	Redirect string
+	// CSRFToken lets the form be posted without htmx
+	CSRFToken string
+}
+
+// ShowControllerList renders the controller list page
+func (h *ControllerHandler) ShowControllerList(c *fiber.Ctx) error {
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "ShowControllerList").
+		Logger()
+
+	includeArchived := c.QueryBool("include_archived")
+
+	controllers, err := h.dbService.ListControllers(c.UserContext(), models.ListControllersParams{
+		IncludeArchived: includeArchived,
 	})
 	if err != nil {
 		reqLogger.Error().
 			Err(err).
-			Int("controller_id", id).
-			Str("template", "controllers/manage").
-			Msg("failed to render controller edit form")
+			Msg("failed to retrieve controllers for list page")
 
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":  "Failed to render form",
-			"detail": err.Error(),
-		})
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve controllers")
 	}
 
-	reqLogger.Info().
-		Int("controller_id", id).
-		Str("controller_name", controller.Name).
-		Msg("controller edit form rendered successfully")
+	facilities, err := h.dbService.ListFacilities(c.UserContext(), models.ListFacilitiesParams{
+		IncludeArchived: true,
+	})
+	if err != nil {
+		reqLogger.Error().
+			Err(err).
+			Msg("failed to retrieve facilities for list page")
+
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve facilities")
+	}
+
+	facilityCodes := make(map[int]string, len(facilities))
+	var active []models.Facility
+	for _, facility := range facilities {
+		facilityCodes[facility.ID] = facility.Code
+		if facility.ArchivedAt == nil {
+			active = append(active, facility)
+		}
+	}
 
-	return nil
+	rows := make([]ControllerRow, 0, len(controllers))
+	for _, controller := range controllers {
+		rows = append(rows, ControllerRow{Controller: controller, FacilityCode: facilityCodes[controller.FacilityID]})
+	}
+
+	reqLogger.Debug().
+		Int("controller_count", len(rows)).
+		Msg("rendering controller list page")
+
+	return c.Render("controllers/index", fiber.Map{
+		"Title":           "Controllers",
+		"Controllers":     rows,
+		"IncludeArchived": includeArchived,
+		"Form":            ControllerForm{Facilities: active, CSRFToken: middleware.CSRFToken(c)},
+	})
 }
 
-// ShowScheduleForm renders the controller schedule form
-func (h *ControllerHandler) ShowScheduleForm(c *fiber.Ctx) error {
+// ShowCreateForm renders the controller creation form
+func (h *ControllerHandler) ShowCreateForm(c *fiber.Ctx) error {
+	form, err := h.controllerForm(c, ControllerForm{Redirect: "/controllers"})
+	if err != nil {
+		return err
+	}
+
+	return c.Render(createControllerPage.name, fiber.Map{
+		"Title": createControllerPage.title,
+		"Form":  form,
+	})
+}
+
+// ShowEditForm renders the controller edit form with preloaded data
+func (h *ControllerHandler) ShowEditForm(c *fiber.Ctx) error {
 	// Create request-specific logger
-	reqLogger := h.logger.With().
-		Str("method", "ShowScheduleForm").
-		Str("request_id", c.GetRespHeader("X-Request-ID")).
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "ShowEditForm").
 		Logger()
 
+	controller, err := pageController(c, h.dbService, reqLogger)
+	if err != nil {
+		return err
+	}
+
+	form, err := h.controllerForm(c, editForm(controller))
+	if err != nil {
+		return err
+	}
+
+	return c.Render(editControllerPage.name, fiber.Map{
+		"Title": editControllerPage.title,
+		"Form":  form,
+	})
+}
+
+// SubmitCreateForm handles the controller creation form. It answers with the
+// form fragment: re-rendered with field errors when the input is invalid, or
+// cleared with the new row appended to the page's list out of band. A form
+// posted without htmx is sent back to its page once saved.
+func (h *ControllerHandler) SubmitCreateForm(c *fiber.Ctx) error {
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "SubmitCreateForm").
+		Logger()
+
+	var form ControllerForm
+	err := c.BodyParser(&form.Values)
+	if err != nil {
+		reqLogger.Warn().
+			Err(err).
+			Msg("failed to parse controller form")
+
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid form submission")
+	}
+	form.Values.Initials = strings.ToUpper(form.Values.Initials)
+	if redirect := c.FormValue("redirect"); localPath(redirect) {
+		form.Redirect = redirect
+	}
+
+	if form.Errors, err = formErrors(validation.Struct(form.Values)); err != nil {
+		return err
+	}
+	if form.Errors != nil {
+		return h.renderControllerForm(c, fiber.StatusUnprocessableEntity, form)
+	}
+
+	controller, err := h.dbService.CreateController(c.UserContext(), form.Values)
+	if err != nil {
+		switch {
+		case isDuplicateKeyError(err):
+			form.Message = "Email or initials already in use at this facility"
+			return h.renderControllerForm(c, fiber.StatusConflict, form)
+		case isForeignKeyError(err):
+			form.Errors = validation.FieldErrors{"facility_id": "must be an existing facility"}
+			return h.renderControllerForm(c, fiber.StatusUnprocessableEntity, form)
+		}
+
+		reqLogger.Error().
+			Err(err).
+			Msg("failed to create controller from form")
+
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to create controller")
+	}
+
 	reqLogger.Info().
-		Str("template", "controllers/schedule").
-		Msg("rendering controller schedule form")
+		Int("controller_id", controller.ID).
+		Int("facility_id", controller.FacilityID).
+		Msg("controller created from form")
 
-	// Validate the ID
-	controllerID := c.Params("id")
-	id, err := strconv.Atoi(controllerID)
+	saved := fmt.Sprintf("Controller %s created", controller.Name)
+	if form.Redirect != "" {
+		return redirectWithFlash(c, form.Redirect, saved)
+	}
+	if !isPartial(c) {
+		return redirectWithFlash(c, returnPath(c, "/controllers"), saved)
+	}
+
+	row, err := h.controllerRow(c, controller)
+	if err != nil {
+		return err
+	}
+
+	return h.renderControllerForm(c, fiber.StatusCreated, ControllerForm{
+		Values:  models.CreateControllerParams{FacilityID: controller.FacilityID},
+		Saved:   saved,
+		Created: row,
+	})
+}
+
+// SubmitEditForm handles the controller edit form. The form carries the
+// version it was rendered from; when someone else saved in between, the
+// form comes back with the current values instead of overwriting them.
+func (h *ControllerHandler) SubmitEditForm(c *fiber.Ctx) error {
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "SubmitEditForm").
+		Logger()
+
+	previous, err := pageController(c, h.dbService, reqLogger)
+	if err != nil {
+		return err
+	}
+
+	form := ControllerForm{Controller: previous}
+	if err := c.BodyParser(&form.Values); err != nil {
+		reqLogger.Warn().
+			Err(err).
+			Msg("failed to parse controller form")
+
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid form submission")
+	}
+	form.Values.Initials = strings.ToUpper(form.Values.Initials)
+
+	form.Version, err = strconv.Atoi(c.FormValue("version"))
+	if err != nil || form.Version <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "The form is missing the controller version; reload the page")
+	}
+
+	if form.Errors, err = formErrors(validation.Struct(form.Values)); err != nil {
+		return err
+	}
+	if form.Errors != nil {
+		return h.renderControllerForm(c, fiber.StatusUnprocessableEntity, form)
+	}
+
+	controller, err := h.dbService.UpdateController(c.UserContext(), previous.ID, form.Version, form.Values)
 	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrStaleVersion):
+			current, getErr := h.dbService.GetControllerByID(c.UserContext(), previous.ID)
+			if getErr != nil {
+				reqLogger.Error().
+					Err(getErr).
+					Int("controller_id", previous.ID).
+					Msg("failed to reload controller after stale update")
+
+				return fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve controller")
+			}
+
+			form = editForm(current)
+			form.Message = "This controller was changed by someone else. Review the current values and save again."
+			return h.renderControllerForm(c, fiber.StatusPreconditionFailed, form)
+		case isNotFoundError(err):
+			return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("No controller found with ID %d", previous.ID))
+		case isDuplicateKeyError(err):
+			form.Message = "Email or initials already in use at this facility"
+			return h.renderControllerForm(c, fiber.StatusConflict, form)
+		case isForeignKeyError(err):
+			form.Errors = validation.FieldErrors{"facility_id": "must be an existing facility"}
+			return h.renderControllerForm(c, fiber.StatusUnprocessableEntity, form)
+		}
+
 		reqLogger.Error().
 			Err(err).
-			Str("id_raw", controllerID).
-			Msg("invalid controller ID format")
+			Int("controller_id", previous.ID).
+			Msg("failed to update controller from form")
 
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":  "Invalid controller ID",
-			"detail": "ID must be a number",
-		})
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to update controller")
 	}
 
-	// Log attempt to fetch controller
-	reqLogger.Debug().
-		Int("controller_id", id).
-		Msg("fetching controller data for edit form")
+	reqLogger.Info().
+		Int("controller_id", controller.ID).
+		Msg("controller updated from form")
+
+	h.calendarService.InvalidateFacility(c.UserContext(), controller.FacilityID)
+	if previous.FacilityID != controller.FacilityID {
+		h.calendarService.InvalidateFacility(c.UserContext(), previous.FacilityID)
+	}
+
+	if !isPartial(c) {
+		return redirectWithFlash(c, fmt.Sprintf("/controllers/%d", controller.ID), "Controller updated")
+	}
+
+	form = editForm(controller)
+	form.Saved = "Controller updated"
+	return h.renderControllerForm(c, fiber.StatusOK, form)
+}
+
+// ArchiveRow archives a controller and answers with its updated list row, or
+// sends a browser without htmx back to the list
+func (h *ControllerHandler) ArchiveRow(c *fiber.Ctx) error {
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "ArchiveRow").
+		Logger()
 
-	// Fetch the controller data
-	controller, err := h.dbService.GetControllerByID(c.Context(), id)
+	controller, err := pageController(c, h.dbService, reqLogger)
 	if err != nil {
+		return err
+	}
+
+	if err := h.dbService.ArchiveController(c.UserContext(), controller.ID); err != nil {
 		if isNotFoundError(err) {
-			reqLogger.Warn().
-				Int("controller_id", id).
-				Msg("controller not found for edit form")
+			return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("No active controller found with ID %d", controller.ID))
+		}
 
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error":  "Controller not found",
-				"detail": fmt.Sprintf("no controller found with ID %d", id),
-			})
+		reqLogger.Error().
+			Err(err).
+			Int("controller_id", controller.ID).
+			Msg("failed to archive controller")
+
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to archive controller")
+	}
+
+	reqLogger.Info().
+		Int("controller_id", controller.ID).
+		Msg("controller archived from list")
+
+	h.calendarService.InvalidateController(c.UserContext(), controller.ID)
+
+	if !isPartial(c) {
+		return redirectWithFlash(c, returnPath(c, "/controllers"), fmt.Sprintf("Controller %s archived", controller.Name))
+	}
+	return h.renderControllerRow(c, reqLogger, controller.ID)
+}
+
+// RestoreRow restores an archived controller and answers with its updated
+// list row, or sends a browser without htmx back to the list
+func (h *ControllerHandler) RestoreRow(c *fiber.Ctx) error {
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "RestoreRow").
+		Logger()
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid controller ID")
+	}
+
+	controller, err := h.dbService.RestoreController(c.UserContext(), id)
+	if err != nil {
+		switch {
+		case isNotFoundError(err):
+			return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("No archived controller found with ID %d", id))
+		case isDuplicateKeyError(err):
+			return fiber.NewError(fiber.StatusConflict, "Email or initials already in use at this facility")
 		}
 
 		reqLogger.Error().
 			Err(err).
 			Int("controller_id", id).
-			Msg("failed to retrieve controller for edit form")
+			Msg("failed to restore controller")
 
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":  "Failed to retrieve controller",
-			"detail": err.Error(),
-		})
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to restore controller")
 	}
 
-	err = c.Render("controllers/schedule", fiber.Map{
-		"Title":      "Edit Controller",
-		"EditMode":   true,
-		"Controller": controller,
-	})
+	reqLogger.Info().
+		Int("controller_id", controller.ID).
+		Msg("controller restored from list")
+
+	h.calendarService.InvalidateFacility(c.UserContext(), controller.FacilityID)
+
+	if !isPartial(c) {
+		return redirectWithFlash(c, returnPath(c, "/controllers"), fmt.Sprintf("Controller %s restored", controller.Name))
+	}
+	return h.renderControllerRow(c, reqLogger, controller.ID)
+}
+
+// editForm prepares the edit form for a controller's current values
+func editForm(controller *models.Controller) ControllerForm {
+	return ControllerForm{
+		Controller: controller,
+		Version:    controller.Version,
+		Values: models.CreateControllerParams{
+			Name:       controller.Name,
+			Initials:   controller.Initials,
+			Email:      controller.Email,
+			FacilityID: controller.FacilityID,
+		},
+	}
+}
+
+// controllerForm fills in the facility options and CSRF token of a
+// controller form
+func (h *ControllerHandler) controllerForm(c *fiber.Ctx, form ControllerForm) (ControllerForm, error) {
+	facilities, err := h.dbService.ListFacilities(c.UserContext(), models.ListFacilitiesParams{})
+	if err != nil {
+		zerolog.Ctx(c.UserContext()).Error().
+			Err(err).
+			Msg("failed to retrieve facilities for controller form")
+
+		return form, fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve facilities")
+	}
+
+	form.Facilities = facilities
+	form.CSRFToken = middleware.CSRFToken(c)
+	return form, nil
+}
+
+// renderControllerForm answers a controller form submission, as the form
+// fragment for htmx or as the form's page
+func (h *ControllerHandler) renderControllerForm(c *fiber.Ctx, status int, form ControllerForm) error {
+	form, err := h.controllerForm(c, form)
+	if err != nil {
+		return err
+	}
+
+	page := createControllerPage
+	if form.Controller != nil {
+		page = editControllerPage
+	}
+	return renderForm(c, status, "controller_form", page, form)
+}
+
+// controllerRow resolves the facility code shown in a controller's list row
+func (h *ControllerHandler) controllerRow(c *fiber.Ctx, controller *models.Controller) (*ControllerRow, error) {
+	facility, err := h.dbService.GetFacilityByID(c.UserContext(), controller.FacilityID)
+	if err != nil {
+		zerolog.Ctx(c.UserContext()).Error().
+			Err(err).
+			Int("facility_id", controller.FacilityID).
+			Msg("failed to retrieve facility for controller row")
+
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve facility")
+	}
+
+	return &ControllerRow{Controller: *controller, FacilityCode: facility.Code}, nil
+}
+
+// renderControllerRow renders the list row fragment for a controller as it
+// is now
+func (h *ControllerHandler) renderControllerRow(c *fiber.Ctx, reqLogger zerolog.Logger, id int) error {
+	controller, err := h.dbService.GetControllerByID(c.UserContext(), id)
 	if err != nil {
 		reqLogger.Error().
 			Err(err).
-			Str("template", "controllers/schedule").
-			Msg("failed to render controller schedule form")
+			Int("controller_id", id).
+			Msg("failed to reload controller for list row")
 
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":  "Failed to render schedule form",
-			"detail": err.Error(),
-		})
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve controller")
+	}
+
+	row, err := h.controllerRow(c, controller)
+	if err != nil {
+		return err
+	}
+
+	return renderPartial(c, fiber.StatusOK, "controller_row", row)
+}
+
+// pageController loads the controller named by the :id route parameter for
+// a browser page. Failures are returned as fiber errors so ErrorHandler can
+// render them as an HTML page.
+func pageController(c *fiber.Ctx, dbService *db.Service, reqLogger zerolog.Logger) (*models.Controller, error) {
+	controllerID := c.Params("id")
+	id, err := strconv.Atoi(controllerID)
+	if err != nil {
+		reqLogger.Warn().
+			Str("id_raw", controllerID).
+			Msg("invalid controller ID format")
+
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid controller ID")
 	}
 
-	reqLogger.Debug().Msg("controller schedule form rendered successfully")
+	controller, err := dbService.GetControllerByID(c.UserContext(), id)
+	if err != nil {
+		if isNotFoundError(err) {
+			reqLogger.Warn().
+				Int("controller_id", id).
+				Msg("controller not found")
+
+			return nil, fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("No controller found with ID %d", id))
+		}
+
+		reqLogger.Error().
+			Err(err).
+			Int("controller_id", id).
+			Msg("failed to retrieve controller")
+
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve controller")
+	}
 
-	return nil
+	return controller, nil
 }
 
-// RegisterRoutes registers all controller routes
+// RegisterRoutes registers the controller JSON API routes
 func (h *ControllerHandler) RegisterRoutes(app *fiber.App) {
 	controllers := app.Group("api/v1/controllers")
 
-	// List all controllers
 	controllers.Get("/", h.ListControllers)
+	controllers.Get("/:id", h.GetController)
 	controllers.Post("/", h.CreateController)
 	controllers.Put("/:id", h.UpdateController)
 	controllers.Delete("/:id", h.DeleteController)
+	controllers.Post("/:id/restore", h.RestoreController)
+	controllers.Delete("/:id/purge", middleware.RequireAdmin(h.dbService), h.PurgeController)
+}
 
-	// Create new controller
-	controllers.Get("/new", h.ShowCreateForm)
-
-	// Update existing controller
-	controllers.Get("/edit/:id", h.ShowEditForm)
-
-	// Assign schedule to controller
-	controllers.Get("/schedule/:id", h.ShowScheduleForm)
+// RegisterPageRoutes registers the controller browser pages and the
+// fragments their forms and list rows swap in
+func (h *ControllerHandler) RegisterPageRoutes(app *fiber.App) {
+	pages := app.Group("/controllers")
+
+	pages.Get("/", h.ShowControllerList)
+	pages.Post("/", h.SubmitCreateForm)
+	// Registered before /:id so new is not captured as an ID
+	pages.Get("/new", h.ShowCreateForm)
+	pages.Get("/:id", h.ShowEditForm)
+	pages.Put("/:id", h.SubmitEditForm)
+	pages.Delete("/:id", h.ArchiveRow)
+	pages.Post("/:id/restore", h.RestoreRow)
 }
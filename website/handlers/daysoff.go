@@ -0,0 +1,294 @@
+// handlers/daysoff.go
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dukerupert/weekend-warrior/db/models"
+	"github.com/dukerupert/weekend-warrior/pkg/validation"
+	"github.com/dukerupert/weekend-warrior/services/calendar"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+)
+
+// Limits on one common days off search
+const (
+	maxCommonDaysOffControllers = 20
+	maxCommonDaysOffRange       = 366
+)
+
+// CommonDaysOffQuery is the query string of a common days off search.
+// Dates are YYYY-MM-DD and the range includes both ends.
+type CommonDaysOffQuery struct {
+	ControllerIDs []int  `query:"controller_id"`
+	From          string `query:"from"`
+	To            string `query:"to"`
+	// Min is how many of the controllers must be off, all of them when 0
+	Min int `query:"min"`
+}
+
+// CommonDaysOff is the result of a common days off search
+type CommonDaysOff struct {
+	From        time.Time            `json:"from"`
+	To          time.Time            `json:"to"`
+	Min         int                  `json:"min"`
+	Controllers []models.Controller  `json:"controllers"`
+	Days        []calendar.CommonDay `json:"days"`
+}
+
+// CommonDaysOffPage is the view model of the common days off page
+type CommonDaysOffPage struct {
+	Query CommonDaysOffQuery
+	// Options lists every active controller for the picker
+	Options  []ControllerRow
+	Selected map[int]bool
+	Errors   validation.FieldErrors
+	// Result is nil until a search has run
+	Result *CommonDaysOff
+	// Initials names the controllers in the result's days
+	Initials map[int]string
+}
+
+// GetCommonDaysOff handles GET requests for the days a group of controllers
+// are off together
+func (h *ScheduleHandler) GetCommonDaysOff(c *fiber.Ctx) error {
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "GetCommonDaysOff").
+		Logger()
+
+	var query CommonDaysOffQuery
+	if err := c.QueryParser(&query); err != nil {
+		reqLogger.Warn().
+			Err(err).
+			Msg("failed to parse common days off query")
+
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Invalid query",
+			"detail": err.Error(),
+		})
+	}
+
+	result, err := h.commonDaysOff(c.UserContext(), query)
+	if err != nil {
+		var fields validation.FieldErrors
+		if errors.As(err, &fields) {
+			reqLogger.Warn().
+				Err(err).
+				Msg("validation failed")
+
+			return validationFailed(c, err)
+		}
+
+		reqLogger.Error().
+			Err(err).
+			Ints("controller_ids", query.ControllerIDs).
+			Msg("failed to find common days off")
+
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to find common days off",
+			"detail": err.Error(),
+		})
+	}
+
+	reqLogger.Info().
+		Int("controller_count", len(result.Controllers)).
+		Int("day_count", len(result.Days)).
+		Msg("common days off found")
+
+	return c.JSON(fiber.Map{
+		"data": result,
+	})
+}
+
+// ShowCommonDaysOff renders the common days off page. The search runs once
+// controllers are picked; invalid searches come back with field errors.
+func (h *ScheduleHandler) ShowCommonDaysOff(c *fiber.Ctx) error {
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "ShowCommonDaysOff").
+		Logger()
+
+	page := CommonDaysOffPage{Selected: map[int]bool{}}
+	if err := c.QueryParser(&page.Query); err != nil {
+		reqLogger.Warn().
+			Err(err).
+			Msg("failed to parse common days off query")
+
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid search")
+	}
+	for _, id := range page.Query.ControllerIDs {
+		page.Selected[id] = true
+	}
+
+	options, err := h.controllerOptions(c.UserContext())
+	if err != nil {
+		reqLogger.Error().
+			Err(err).
+			Msg("failed to list controllers for common days off")
+
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve controllers")
+	}
+	page.Options = options
+
+	status := fiber.StatusOK
+	if len(page.Query.ControllerIDs) == 0 {
+		// Nothing picked yet, offer the next three months
+		today := time.Now()
+		page.Query.From = today.Format(time.DateOnly)
+		page.Query.To = today.AddDate(0, 3, 0).Format(time.DateOnly)
+	} else {
+		page.Result, err = h.commonDaysOff(c.UserContext(), page.Query)
+		if page.Errors, err = formErrors(err); err != nil {
+			reqLogger.Error().
+				Err(err).
+				Ints("controller_ids", page.Query.ControllerIDs).
+				Msg("failed to find common days off")
+
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to find common days off")
+		}
+		if page.Errors != nil {
+			status = fiber.StatusUnprocessableEntity
+		}
+	}
+
+	if page.Result != nil {
+		page.Initials = map[int]string{}
+		for _, controller := range page.Result.Controllers {
+			page.Initials[controller.ID] = controller.Initials
+		}
+	}
+
+	return c.Status(status).Render("days_off", fiber.Map{
+		"Title": "Common Days Off",
+		"Page":  page,
+	})
+}
+
+// commonDaysOff validates a common days off search and runs it. Problems
+// with the query are returned as validation.FieldErrors.
+func (h *ScheduleHandler) commonDaysOff(ctx context.Context, query CommonDaysOffQuery) (*CommonDaysOff, error) {
+	fields := validation.FieldErrors{}
+
+	var ids []int
+	seen := map[int]bool{}
+	for _, id := range query.ControllerIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	switch {
+	case len(ids) < 2:
+		fields["controller_id"] = "must name at least 2 controllers"
+	case len(ids) > maxCommonDaysOffControllers:
+		fields["controller_id"] = fmt.Sprintf("must name at most %d controllers", maxCommonDaysOffControllers)
+	}
+
+	from, fromErr := parseDate(query.From)
+	if fromErr != "" {
+		fields["from"] = fromErr
+	}
+	to, toErr := parseDate(query.To)
+	if toErr != "" {
+		fields["to"] = toErr
+	}
+	if fromErr == "" && toErr == "" {
+		switch {
+		case to.Before(from):
+			fields["to"] = "must not be before from"
+		case to.Sub(from) >= maxCommonDaysOffRange*24*time.Hour:
+			fields["to"] = fmt.Sprintf("must be within %d days of from", maxCommonDaysOffRange)
+		}
+	}
+
+	minOff := query.Min
+	if minOff == 0 {
+		minOff = len(ids)
+	}
+	if minOff < 1 || minOff > len(ids) {
+		fields["min"] = fmt.Sprintf("must be between 1 and the number of controllers (%d)", len(ids))
+	}
+
+	if len(fields) > 0 {
+		return nil, fields
+	}
+
+	schedules, err := h.dbService.GetControllerSchedules(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// Everyone must have a schedule, or the days would not be common
+	if len(schedules) != len(ids) {
+		found := map[int]bool{}
+		for _, cs := range schedules {
+			found[cs.Controller.ID] = true
+		}
+		var missing []string
+		for _, id := range ids {
+			if !found[id] {
+				missing = append(missing, strconv.Itoa(id))
+			}
+		}
+		return nil, validation.FieldErrors{
+			"controller_id": "has no active controller with a schedule for ID " + strings.Join(missing, ", "),
+		}
+	}
+
+	result := &CommonDaysOff{
+		From:        from,
+		To:          to,
+		Min:         minOff,
+		Controllers: make([]models.Controller, 0, len(schedules)),
+		Days:        h.calendarService.CommonDaysOff(ctx, schedules, from, to, minOff),
+	}
+	for _, cs := range schedules {
+		result.Controllers = append(result.Controllers, cs.Controller)
+	}
+
+	return result, nil
+}
+
+// controllerOptions lists every active controller with their facility code,
+// for controller pickers
+func (h *ScheduleHandler) controllerOptions(ctx context.Context) ([]ControllerRow, error) {
+	controllers, err := h.dbService.ListControllers(ctx, models.ListControllersParams{})
+	if err != nil {
+		return nil, err
+	}
+
+	facilities, err := h.dbService.ListFacilities(ctx, models.ListFacilitiesParams{})
+	if err != nil {
+		return nil, err
+	}
+	codes := map[int]string{}
+	for _, facility := range facilities {
+		codes[facility.ID] = facility.Code
+	}
+
+	options := make([]ControllerRow, 0, len(controllers))
+	for _, controller := range controllers {
+		options = append(options, ControllerRow{Controller: controller, FacilityCode: codes[controller.FacilityID]})
+	}
+	return options, nil
+}
+
+// parseDate parses a YYYY-MM-DD query value, returning a field error
+// message when it is missing or malformed
+func parseDate(value string) (time.Time, string) {
+	if value == "" {
+		return time.Time{}, "is required"
+	}
+
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, "must be a date"
+	}
+	return date, ""
+}
@@ -0,0 +1,80 @@
+// handlers/errors.go
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dukerupert/weekend-warrior/middleware"
+	"github.com/dukerupert/weekend-warrior/website/openapi"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/rs/zerolog"
+)
+
+// ErrorHandler renders errors returned from handlers and middleware. Browser
+// requests outside the API get an HTML error page, or an alert fragment when
+// htmx asked for a partial; everything else gets the API's error envelope. Internal errors do not expose their cause; the request ID lets
+// the detail be found in the logs.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	code := fiber.StatusInternalServerError
+	message := "Internal Server Error"
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		code = fiberErr.Code
+		message = fiberErr.Message
+	}
+
+	detail := message
+	if code >= fiber.StatusInternalServerError {
+		detail = fmt.Sprintf("an unexpected error occurred (request ID %s)", middleware.GetRequestID(c))
+	}
+
+	if wantsHTML(c) {
+		var renderErr error
+		if isPartial(c) {
+			// Fragments for htmx land in the page's flash area
+			c.Set(headerHXRetarget, flashTarget)
+			c.Set(headerHXReswap, "innerHTML")
+			renderErr = renderPartial(c, code, "alert", fiber.Map{
+				"Message": message,
+				"Detail":  detail,
+			})
+		} else {
+			renderErr = c.Status(code).Render("error", fiber.Map{
+				"Title":     fmt.Sprintf("%d %s", code, utils.StatusMessage(code)),
+				"Status":    code,
+				"Message":   message,
+				"Detail":    detail,
+				"RequestID": middleware.GetRequestID(c),
+			})
+		}
+		if renderErr == nil {
+			return nil
+		}
+
+		zerolog.Ctx(c.UserContext()).Error().
+			Err(renderErr).
+			Msg("failed to render error page")
+	}
+
+	return c.Status(code).JSON(fiber.Map{
+		"error":  message,
+		"detail": detail,
+	})
+}
+
+// wantsHTML reports whether the request comes from a browser page rather
+// than an API client. API routes always answer in JSON; htmx requests
+// always want HTML, whatever they accept.
+func wantsHTML(c *fiber.Ctx) bool {
+	if strings.HasPrefix(c.Path(), openapi.Prefix) {
+		return false
+	}
+	if isPartial(c) {
+		return true
+	}
+	return c.Accepts(fiber.MIMEApplicationJSON, fiber.MIMETextHTML) == fiber.MIMETextHTML
+}
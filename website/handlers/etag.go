@@ -0,0 +1,49 @@
+// handlers/etag.go
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	errMissingIfMatch = errors.New("If-Match header is required")
+	errInvalidIfMatch = errors.New("If-Match header must be an ETag returned by a GET")
+)
+
+// setETag sets a strong ETag derived from a row version
+func setETag(c *fiber.Ctx, version int) {
+	c.Set(fiber.HeaderETag, fmt.Sprintf(`"%d"`, version))
+}
+
+// ifMatchVersion reads the row version a client expects from the If-Match header
+func ifMatchVersion(c *fiber.Ctx) (int, error) {
+	header := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if header == "" {
+		return 0, errMissingIfMatch
+	}
+
+	version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(header, "W/"), `"`))
+	if err != nil || version <= 0 {
+		return 0, errInvalidIfMatch
+	}
+
+	return version, nil
+}
+
+// preconditionError responds to a missing or unparseable If-Match header
+func preconditionError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusPreconditionFailed
+	if errors.Is(err, errMissingIfMatch) {
+		status = fiber.StatusPreconditionRequired
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"error":  "Precondition failed",
+		"detail": err.Error(),
+	})
+}
@@ -2,47 +2,54 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/dukerupert/weekend-warrior/db"
 	"github.com/dukerupert/weekend-warrior/db/models"
+	"github.com/dukerupert/weekend-warrior/middleware"
+	"github.com/dukerupert/weekend-warrior/pkg/validation"
 	"github.com/gofiber/fiber/v2"
 	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
 )
 
 // FacilityHandler handles HTTP requests for facilities
 type FacilityHandler struct {
 	dbService *db.Service
-	logger    zerolog.Logger
 }
 
 // NewFacilityHandler creates a new facility handler
 func NewFacilityHandler(dbService *db.Service) *FacilityHandler {
 	return &FacilityHandler{
 		dbService: dbService,
-		logger:    log.With().Str("handler", "facility").Logger(),
 	}
 }
 
 // CreateFacilityRequest represents the request body for creating a facility
 type CreateFacilityRequest struct {
-	Name string `json:"name"`
-	Code string `json:"code"`
+	Name      string `json:"name"`
+	Code      string `json:"code"`
+	MinOnDuty *int   `json:"min_on_duty"`
 }
 
 // ListFacilities handles GET requests to list all facilities
 func (h *FacilityHandler) ListFacilities(c *fiber.Ctx) error {
 	// Create request-specific logger
-	reqLogger := h.logger.With().
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
 		Str("method", "ListFacilities").
-		Str("request_id", c.GetRespHeader("X-Request-ID")).
 		Logger()
 
-	reqLogger.Info().Msg("retrieving facilities list")
+	params := models.ListFacilitiesParams{
+		IncludeArchived: c.QueryBool("include_archived"),
+	}
+
+	reqLogger.Info().
+		Bool("include_archived", params.IncludeArchived).
+		Msg("retrieving facilities list")
 
-	facilities, err := h.dbService.ListFacilities(c.Context())
+	facilities, err := h.dbService.ListFacilities(c.UserContext(), params)
 	if err != nil {
 		reqLogger.Error().
 			Err(err).
@@ -66,9 +73,8 @@ func (h *FacilityHandler) ListFacilities(c *fiber.Ctx) error {
 // CreateFacility handles POST requests to create a new facility
 func (h *FacilityHandler) CreateFacility(c *fiber.Ctx) error {
 	// Create request-specific logger
-	reqLogger := h.logger.With().
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
 		Str("method", "CreateFacility").
-		Str("request_id", c.GetRespHeader("X-Request-ID")).
 		Logger()
 
 	reqLogger.Info().Msg("processing create facility request")
@@ -77,7 +83,6 @@ func (h *FacilityHandler) CreateFacility(c *fiber.Ctx) error {
 	if err := c.BodyParser(&req); err != nil {
 		reqLogger.Error().
 			Err(err).
-			Str("body", string(c.Body())).
 			Msg("failed to parse request body")
 
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -86,67 +91,43 @@ func (h *FacilityHandler) CreateFacility(c *fiber.Ctx) error {
 		})
 	}
 
-	// Validation logging
-	if req.Name == "" {
-		reqLogger.Error().
-			Interface("request", req).
-			Msg("validation failed: name is required")
-
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":  "Invalid request",
-			"detail": "name is required",
-		})
+	params := models.CreateFacilityParams{
+		Name:      req.Name,
+		Code:      strings.ToUpper(req.Code),
+		MinOnDuty: req.MinOnDuty,
 	}
 
-	if req.Code == "" {
-		reqLogger.Error().
-			Interface("request", req).
-			Msg("validation failed: code is required")
+	if err := validation.Struct(params); err != nil {
+		reqLogger.Warn().
+			Err(err).
+			Msg("validation failed")
 
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":  "Invalid request",
-			"detail": "code is required",
-		})
-	}
-
-	if len(req.Code) != 4 {
-		reqLogger.Error().
-			Str("code", req.Code).
-			Interface("request", req).
-			Msg("validation failed: invalid code length")
-
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":  "Invalid request",
-			"detail": "code must be exactly 4 characters",
-		})
+		return validationFailed(c, err)
 	}
 
 	reqLogger.Debug().
-		Str("name", req.Name).
-		Str("code", req.Code).
+		Str("name", params.Name).
+		Str("code", params.Code).
 		Msg("attempting to create facility")
 
-	facility, err := h.dbService.CreateFacility(c.Context(), models.CreateFacilityParams{
-		Name: req.Name,
-		Code: req.Code,
-	})
+	facility, err := h.dbService.CreateFacility(c.UserContext(), params)
 	if err != nil {
 		if isDuplicateKeyError(err) {
 			reqLogger.Warn().
-				Str("code", req.Code).
-				Str("name", req.Name).
+				Str("code", params.Code).
+				Str("name", params.Name).
 				Msg("duplicate facility code detected")
 
 			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
 				"error":  "Facility code already exists",
-				"detail": fmt.Sprintf("code %s is already in use", req.Code),
+				"detail": fmt.Sprintf("code %s is already in use", params.Code),
 			})
 		}
 
 		reqLogger.Error().
 			Err(err).
-			Str("name", req.Name).
-			Str("code", req.Code).
+			Str("name", params.Name).
+			Str("code", params.Code).
 			Msg("failed to create facility")
 
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -161,17 +142,17 @@ func (h *FacilityHandler) CreateFacility(c *fiber.Ctx) error {
 		Str("code", facility.Code).
 		Msg("facility created successfully")
 
+	setETag(c, facility.Version)
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"data": facility,
 	})
 }
 
-// DeleteFacility handles DELETE requests to delete a facility
+// DeleteFacility handles DELETE requests to archive a facility
 func (h *FacilityHandler) DeleteFacility(c *fiber.Ctx) error {
 	// Create request-specific logger
-	reqLogger := h.logger.With().
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
 		Str("method", "DeleteFacility").
-		Str("request_id", c.GetRespHeader("X-Request-ID")).
 		Logger()
 
 	reqLogger.Info().Msg("processing delete facility request")
@@ -192,64 +173,911 @@ func (h *FacilityHandler) DeleteFacility(c *fiber.Ctx) error {
 
 	reqLogger.Debug().
 		Int("facility_id", id).
-		Msg("attempting to delete facility")
+		Msg("attempting to archive facility")
 
-	err = h.dbService.DeleteFacility(c.Context(), id)
+	err = h.dbService.ArchiveFacility(c.UserContext(), id)
 	if err != nil {
-		if err.Error() == fmt.Sprintf("facility with id %s not found", id) {
+		if isNotFoundError(err) {
 			reqLogger.Warn().
 				Int("facility_id", id).
-				Msg("facility not found for deletion")
+				Msg("facility not found for archiving")
 
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error":  "Facility not found",
-				"detail": fmt.Sprintf("no facility found with id %s", id),
+				"detail": fmt.Sprintf("no active facility found with ID %d", id),
 			})
 		}
 
 		reqLogger.Error().
 			Err(err).
 			Int("facility_id", id).
-			Msg("failed to delete facility")
+			Msg("failed to archive facility")
 
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":  "Failed to delete facility",
+			"error":  "Failed to archive facility",
 			"detail": err.Error(),
 		})
 	}
 
 	reqLogger.Info().
 		Int("facility_id", id).
-		Msg("facility deleted successfully")
+		Msg("facility archived successfully")
 
 	return c.Status(fiber.StatusNoContent).Send(nil)
 }
 
-// ShowCreateForm renders the facility creation form
-func (h *FacilityHandler) ShowCreateForm(c *fiber.Ctx) error {
-	return c.Render("facilities/create", fiber.Map{
-		"Title": "Create New Facility",
+// RestoreFacility handles POST requests to restore an archived facility
+func (h *FacilityHandler) RestoreFacility(c *fiber.Ctx) error {
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "RestoreFacility").
+		Logger()
+
+	reqLogger.Info().Msg("processing restore facility request")
+
+	// Parse and validate ID
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		reqLogger.Error().
+			Err(err).
+			Str("id_raw", c.Params("id")).
+			Msg("invalid facility ID format")
+
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Invalid facility ID",
+			"detail": "ID must be a number",
+		})
+	}
+
+	facility, err := h.dbService.RestoreFacility(c.UserContext(), id)
+	if err != nil {
+		if isNotFoundError(err) {
+			reqLogger.Warn().
+				Int("facility_id", id).
+				Msg("archived facility not found for restore")
+
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":  "Facility not found",
+				"detail": fmt.Sprintf("no archived facility found with ID %d", id),
+			})
+		}
+
+		reqLogger.Error().
+			Err(err).
+			Int("facility_id", id).
+			Msg("failed to restore facility")
+
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to restore facility",
+			"detail": err.Error(),
+		})
+	}
+
+	reqLogger.Info().
+		Int("facility_id", facility.ID).
+		Str("code", facility.Code).
+		Msg("facility restored successfully")
+
+	return c.JSON(fiber.Map{
+		"data": facility,
+	})
+}
+
+// PurgeFacility handles DELETE requests to permanently remove an archived
+// facility with its controllers, schedules and role assignments. With
+// dry_run=true it only reports what would be removed along with a
+// confirmation token; the real purge requires that token in the confirm
+// query parameter.
+func (h *FacilityHandler) PurgeFacility(c *fiber.Ctx) error {
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "PurgeFacility").
+		Logger()
+
+	reqLogger.Info().Msg("processing purge facility request")
+
+	// Parse and validate ID
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		reqLogger.Error().
+			Err(err).
+			Str("id_raw", c.Params("id")).
+			Msg("invalid facility ID format")
+
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Invalid facility ID",
+			"detail": "ID must be a number",
+		})
+	}
+
+	dryRun := c.QueryBool("dry_run")
+	token := c.Query("confirm")
+
+	if !dryRun && token == "" {
+		reqLogger.Warn().
+			Int("facility_id", id).
+			Msg("purge requested without confirmation token")
+
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Confirmation required",
+			"detail": "run with dry_run=true first, then pass the returned confirmation_token as confirm",
+		})
+	}
+
+	var plan *models.FacilityPurgePlan
+	if dryRun {
+		plan, err = h.dbService.PreviewFacilityPurge(c.UserContext(), id)
+	} else {
+		plan, err = h.dbService.PurgeFacility(c.UserContext(), id, token)
+	}
+	if err != nil {
+		if errors.Is(err, db.ErrPurgeTokenMismatch) {
+			reqLogger.Warn().
+				Int("facility_id", id).
+				Msg("purge confirmation token is stale")
+
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":  "Confirmation token is stale",
+				"detail": "the affected rows changed since the preview; run dry_run=true again",
+			})
+		}
+
+		if isNotFoundError(err) {
+			reqLogger.Warn().
+				Int("facility_id", id).
+				Msg("archived facility not found for purge")
+
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":  "Facility not found",
+				"detail": fmt.Sprintf("no archived facility found with ID %d; archive it before purging", id),
+			})
+		}
+
+		reqLogger.Error().
+			Err(err).
+			Int("facility_id", id).
+			Bool("dry_run", dryRun).
+			Msg("failed to purge facility")
+
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to purge facility",
+			"detail": err.Error(),
+		})
+	}
+
+	reqLogger.Info().
+		Int("facility_id", id).
+		Bool("dry_run", dryRun).
+		Int("controller_count", len(plan.Controllers)).
+		Int("schedule_count", len(plan.ScheduleIDs)).
+		Int("role_assignment_count", len(plan.RoleAssignmentIDs)).
+		Msg("facility purge processed successfully")
+
+	return c.JSON(fiber.Map{
+		"data":    plan,
+		"dry_run": dryRun,
+	})
+}
+
+// GetFacility handles GET requests to retrieve a facility by its code
+func (h *FacilityHandler) GetFacility(c *fiber.Ctx) error {
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "GetFacility").
+		Logger()
+
+	code := strings.ToUpper(c.Params("code"))
+
+	reqLogger.Info().
+		Str("code", code).
+		Msg("processing get facility request")
+
+	facility, err := h.dbService.GetFacilityByCode(c.UserContext(), code)
+	if err != nil {
+		if isNotFoundError(err) {
+			reqLogger.Warn().
+				Str("code", code).
+				Msg("facility not found")
+
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":  "Facility not found",
+				"detail": fmt.Sprintf("no facility found with code %s", code),
+			})
+		}
+
+		reqLogger.Error().
+			Err(err).
+			Str("code", code).
+			Msg("failed to retrieve facility")
+
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to retrieve facility",
+			"detail": err.Error(),
+		})
+	}
+
+	reqLogger.Info().
+		Int("facility_id", facility.ID).
+		Str("code", facility.Code).
+		Msg("facility retrieved successfully")
+
+	setETag(c, facility.Version)
+	return c.JSON(fiber.Map{
+		"data": facility,
+	})
+}
+
+// UpdateFacility handles PUT requests to update a facility's settings
+func (h *FacilityHandler) UpdateFacility(c *fiber.Ctx) error {
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "UpdateFacility").
+		Logger()
+
+	reqLogger.Info().Msg("processing update facility request")
+
+	// Parse and validate ID
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		reqLogger.Error().
+			Err(err).
+			Str("id_raw", c.Params("id")).
+			Msg("invalid facility ID format")
+
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Invalid facility ID",
+			"detail": "ID must be a number",
+		})
+	}
+
+	// The client must name the version it last read
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		reqLogger.Warn().
+			Err(err).
+			Int("facility_id", id).
+			Msg("update rejected without a usable If-Match header")
+
+		return preconditionError(c, err)
+	}
+
+	var params models.UpdateFacilityParams
+	if err := c.BodyParser(&params); err != nil {
+		reqLogger.Error().
+			Err(err).
+			Msg("failed to parse request body")
+
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Invalid request body",
+			"detail": err.Error(),
+		})
+	}
+	params.Code = strings.ToUpper(params.Code)
+
+	if err := validation.Struct(params); err != nil {
+		reqLogger.Warn().
+			Err(err).
+			Int("facility_id", id).
+			Msg("validation failed")
+
+		return validationFailed(c, err)
+	}
+
+	reqLogger.Debug().
+		Int("facility_id", id).
+		Str("name", params.Name).
+		Str("code", params.Code).
+		Msg("attempting to update facility")
+
+	facility, err := h.dbService.UpdateFacility(c.UserContext(), id, version, params)
+	if err != nil {
+		if errors.Is(err, db.ErrStaleVersion) {
+			reqLogger.Warn().
+				Int("facility_id", id).
+				Int("version", version).
+				Msg("facility update rejected, version is stale")
+
+			current, getErr := h.dbService.GetFacilityByID(c.UserContext(), id)
+			if getErr != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error":  "Failed to retrieve facility",
+					"detail": getErr.Error(),
+				})
+			}
+
+			setETag(c, current.Version)
+			return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+				"error":  "Facility has changed",
+				"detail": "the facility was modified by someone else; review the current version and retry",
+				"data":   current,
+			})
+		}
+
+		if isNotFoundError(err) {
+			reqLogger.Warn().
+				Int("facility_id", id).
+				Msg("facility not found for update")
+
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":  "Facility not found",
+				"detail": fmt.Sprintf("no facility found with ID %d", id),
+			})
+		}
+
+		if isDuplicateKeyError(err) {
+			reqLogger.Warn().
+				Int("facility_id", id).
+				Str("code", params.Code).
+				Msg("duplicate facility code detected during update")
+
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":  "Facility code already exists",
+				"detail": fmt.Sprintf("code %s is already in use", params.Code),
+			})
+		}
+
+		reqLogger.Error().
+			Err(err).
+			Int("facility_id", id).
+			Msg("failed to update facility")
+
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to update facility",
+			"detail": err.Error(),
+		})
+	}
+
+	reqLogger.Info().
+		Int("facility_id", facility.ID).
+		Str("name", facility.Name).
+		Str("code", facility.Code).
+		Msg("facility updated successfully")
+
+	setETag(c, facility.Version)
+	return c.JSON(fiber.Map{
+		"data": facility,
 	})
 }
 
 // GetFacilityControllers returns all controllers for a facility code
 func (h *FacilityHandler) GetFacilityControllers(c *fiber.Ctx) error {
-	return c.Render("facilities/create", fiber.Map{
-		"Title": "Facility Controller List",
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "GetFacilityControllers").
+		Logger()
+
+	code := strings.ToUpper(c.Params("code"))
+
+	reqLogger.Info().
+		Str("code", code).
+		Msg("retrieving controllers at facility")
+
+	facility, err := h.dbService.GetFacilityByCode(c.UserContext(), code)
+	if err != nil {
+		if isNotFoundError(err) {
+			reqLogger.Warn().
+				Str("code", code).
+				Msg("facility not found")
+
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":  "Facility not found",
+				"detail": fmt.Sprintf("no facility found with code %s", code),
+			})
+		}
+
+		reqLogger.Error().
+			Err(err).
+			Str("code", code).
+			Msg("failed to retrieve facility")
+
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to retrieve facility",
+			"detail": err.Error(),
+		})
+	}
+
+	controllers, err := h.dbService.GetControllersByFacility(c.UserContext(), facility.ID, models.ListControllersParams{
+		IncludeArchived: c.QueryBool("include_archived"),
+	})
+	if err != nil {
+		reqLogger.Error().
+			Err(err).
+			Int("facility_id", facility.ID).
+			Msg("failed to retrieve controllers at facility")
+
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to retrieve controllers",
+			"detail": err.Error(),
+		})
+	}
+
+	reqLogger.Info().
+		Int("facility_id", facility.ID).
+		Int("controller_count", len(controllers)).
+		Msg("controllers at facility retrieved successfully")
+
+	return c.JSON(fiber.Map{
+		"data": controllers,
+	})
+}
+
+// FacilityForm holds what the facility form fragment renders
+type FacilityForm struct {
+	// Facility is the facility being edited, nil on the creation form
+	Facility *models.Facility
+	Version  int
+	Values   models.CreateFacilityParams
+	Errors   validation.FieldErrors
+	// Message is a problem with the whole form, Saved a success notice
+	Message string
+	Saved   string
+	// Created is appended to the page's facility list out of band
+	Created *models.Facility
+	// Redirect is the page to show once the facility is created, for forms
+	// that are not next to the facility list
+	Redirect string
+	// CSRFToken lets the form be posted without htmx
+	CSRFToken string
+}
+
+// MinOnDuty is the staffing minimum the form shows, the default on a blank
+// creation form
+func (f FacilityForm) MinOnDuty() int {
+	if f.Values.MinOnDuty != nil {
+		return *f.Values.MinOnDuty
+	}
+	return models.DefaultMinOnDuty
+}
+
+// ShowFacilityList renders the facility list page
+func (h *FacilityHandler) ShowFacilityList(c *fiber.Ctx) error {
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "ShowFacilityList").
+		Logger()
+
+	includeArchived := c.QueryBool("include_archived")
+
+	facilities, err := h.dbService.ListFacilities(c.UserContext(), models.ListFacilitiesParams{
+		IncludeArchived: includeArchived,
+	})
+	if err != nil {
+		reqLogger.Error().
+			Err(err).
+			Msg("failed to retrieve facilities for list page")
+
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve facilities")
+	}
+
+	reqLogger.Debug().
+		Int("facility_count", len(facilities)).
+		Msg("rendering facility list page")
+
+	return c.Render("facilities/index", fiber.Map{
+		"Title":           "Facilities",
+		"Facilities":      facilities,
+		"IncludeArchived": includeArchived,
+		"Form":            FacilityForm{CSRFToken: middleware.CSRFToken(c)},
+	})
+}
+
+// ShowCreateForm renders the facility creation form
+func (h *FacilityHandler) ShowCreateForm(c *fiber.Ctx) error {
+	return c.Render(createFacilityPage.name, fiber.Map{
+		"Title": createFacilityPage.title,
+		"Form":  FacilityForm{Redirect: "/facilities", CSRFToken: middleware.CSRFToken(c)},
+	})
+}
+
+// ShowEditForm renders the facility edit form with preloaded data
+func (h *FacilityHandler) ShowEditForm(c *fiber.Ctx) error {
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "ShowEditForm").
+		Logger()
+
+	code := strings.ToUpper(c.Params("code"))
+
+	facility, err := h.dbService.GetFacilityByCode(c.UserContext(), code)
+	if err != nil {
+		if isNotFoundError(err) {
+			reqLogger.Warn().
+				Str("code", code).
+				Msg("facility not found")
+
+			return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("No facility found with code %s", code))
+		}
+
+		reqLogger.Error().
+			Err(err).
+			Str("code", code).
+			Msg("failed to retrieve facility")
+
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve facility")
+	}
+
+	form := editFacilityForm(facility)
+	form.CSRFToken = middleware.CSRFToken(c)
+
+	return c.Render(editFacilityPage.name, fiber.Map{
+		"Title": editFacilityPage.title,
+		"Form":  form,
+	})
+}
+
+// ShowFacility renders a facility page listing its controllers
+func (h *FacilityHandler) ShowFacility(c *fiber.Ctx) error {
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "ShowFacility").
+		Logger()
+
+	code := strings.ToUpper(c.Params("code"))
+
+	facility, err := h.dbService.GetFacilityByCode(c.UserContext(), code)
+	if err != nil {
+		if isNotFoundError(err) {
+			reqLogger.Warn().
+				Str("code", code).
+				Msg("facility not found")
+
+			return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("No facility found with code %s", code))
+		}
+
+		reqLogger.Error().
+			Err(err).
+			Str("code", code).
+			Msg("failed to retrieve facility")
+
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve facility")
+	}
+
+	controllers, err := h.dbService.GetControllersByFacility(c.UserContext(), facility.ID, models.ListControllersParams{
+		IncludeArchived: c.QueryBool("include_archived"),
+	})
+	if err != nil {
+		reqLogger.Error().
+			Err(err).
+			Int("facility_id", facility.ID).
+			Msg("failed to retrieve controllers at facility")
+
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve controllers")
+	}
+
+	facilities, err := h.dbService.ListFacilities(c.UserContext(), models.ListFacilitiesParams{})
+	if err != nil {
+		reqLogger.Error().
+			Err(err).
+			Msg("failed to retrieve facilities for controller form")
+
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve facilities")
+	}
+
+	rows := make([]ControllerRow, 0, len(controllers))
+	for _, controller := range controllers {
+		rows = append(rows, ControllerRow{Controller: controller, FacilityCode: facility.Code})
+	}
+
+	return c.Render("facilities/controllers", fiber.Map{
+		"Title":       fmt.Sprintf("%s Controllers", facility.Code),
+		"Facility":    facility,
+		"Controllers": rows,
+		"Form": ControllerForm{
+			Values:     models.CreateControllerParams{FacilityID: facility.ID},
+			Facilities: facilities,
+			CSRFToken:  middleware.CSRFToken(c),
+		},
+	})
+}
+
+// SubmitCreateForm handles the facility creation form. It answers with the
+// form fragment: re-rendered with field errors when the input is invalid, or
+// cleared with the new row appended to the page's list out of band. A form
+// posted without htmx is sent back to its page once saved.
+func (h *FacilityHandler) SubmitCreateForm(c *fiber.Ctx) error {
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "SubmitCreateForm").
+		Logger()
+
+	var form FacilityForm
+	err := c.BodyParser(&form.Values)
+	if err != nil {
+		reqLogger.Warn().
+			Err(err).
+			Msg("failed to parse facility form")
+
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid form submission")
+	}
+	form.Values.Code = strings.ToUpper(form.Values.Code)
+	if redirect := c.FormValue("redirect"); localPath(redirect) {
+		form.Redirect = redirect
+	}
+
+	if form.Errors, err = formErrors(validation.Struct(form.Values)); err != nil {
+		return err
+	}
+	if form.Errors != nil {
+		return renderFacilityForm(c, fiber.StatusUnprocessableEntity, form)
+	}
+
+	facility, err := h.dbService.CreateFacility(c.UserContext(), form.Values)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			form.Errors = validation.FieldErrors{"code": fmt.Sprintf("%s is already in use", form.Values.Code)}
+			return renderFacilityForm(c, fiber.StatusConflict, form)
+		}
+
+		reqLogger.Error().
+			Err(err).
+			Msg("failed to create facility from form")
+
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to create facility")
+	}
+
+	reqLogger.Info().
+		Int("facility_id", facility.ID).
+		Str("code", facility.Code).
+		Msg("facility created from form")
+
+	saved := fmt.Sprintf("Facility %s created", facility.Code)
+	if form.Redirect != "" {
+		return redirectWithFlash(c, form.Redirect, saved)
+	}
+	if !isPartial(c) {
+		return redirectWithFlash(c, returnPath(c, "/facilities"), saved)
+	}
+
+	return renderFacilityForm(c, fiber.StatusCreated, FacilityForm{
+		Saved:   saved,
+		Created: facility,
 	})
 }
 
-// RegisterRoutes registers all facility routes
+// SubmitEditForm handles the facility edit form. The form carries the
+// version it was rendered from; when someone else saved in between, the
+// form comes back with the current values instead of overwriting them.
+func (h *FacilityHandler) SubmitEditForm(c *fiber.Ctx) error {
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "SubmitEditForm").
+		Logger()
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid facility ID")
+	}
+
+	previous, err := h.dbService.GetFacilityByID(c.UserContext(), id)
+	if err != nil {
+		if isNotFoundError(err) {
+			return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("No facility found with ID %d", id))
+		}
+
+		reqLogger.Error().
+			Err(err).
+			Int("facility_id", id).
+			Msg("failed to retrieve facility")
+
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve facility")
+	}
+
+	form := FacilityForm{Facility: previous}
+	if err := c.BodyParser(&form.Values); err != nil {
+		reqLogger.Warn().
+			Err(err).
+			Msg("failed to parse facility form")
+
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid form submission")
+	}
+	form.Values.Code = strings.ToUpper(form.Values.Code)
+
+	form.Version, err = strconv.Atoi(c.FormValue("version"))
+	if err != nil || form.Version <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "The form is missing the facility version; reload the page")
+	}
+
+	if form.Errors, err = formErrors(validation.Struct(form.Values)); err != nil {
+		return err
+	}
+	if form.Errors != nil {
+		return renderFacilityForm(c, fiber.StatusUnprocessableEntity, form)
+	}
+
+	facility, err := h.dbService.UpdateFacility(c.UserContext(), id, form.Version, models.UpdateFacilityParams(form.Values))
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrStaleVersion):
+			current, getErr := h.dbService.GetFacilityByID(c.UserContext(), id)
+			if getErr != nil {
+				reqLogger.Error().
+					Err(getErr).
+					Int("facility_id", id).
+					Msg("failed to reload facility after stale update")
+
+				return fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve facility")
+			}
+
+			form = editFacilityForm(current)
+			form.Message = "This facility was changed by someone else. Review the current values and save again."
+			return renderFacilityForm(c, fiber.StatusPreconditionFailed, form)
+		case isNotFoundError(err):
+			return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("No facility found with ID %d", id))
+		case isDuplicateKeyError(err):
+			form.Errors = validation.FieldErrors{"code": fmt.Sprintf("%s is already in use", form.Values.Code)}
+			return renderFacilityForm(c, fiber.StatusConflict, form)
+		}
+
+		reqLogger.Error().
+			Err(err).
+			Int("facility_id", id).
+			Msg("failed to update facility from form")
+
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to update facility")
+	}
+
+	reqLogger.Info().
+		Int("facility_id", facility.ID).
+		Msg("facility updated from form")
+
+	if !isPartial(c) {
+		return redirectWithFlash(c, "/facilities/"+facility.Code+"/edit", "Facility updated")
+	}
+
+	form = editFacilityForm(facility)
+	form.Saved = "Facility updated"
+	return renderFacilityForm(c, fiber.StatusOK, form)
+}
+
+// ArchiveRow archives a facility and answers with its updated list row, or
+// sends a browser without htmx back to the list
+func (h *FacilityHandler) ArchiveRow(c *fiber.Ctx) error {
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "ArchiveRow").
+		Logger()
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid facility ID")
+	}
+
+	if err := h.dbService.ArchiveFacility(c.UserContext(), id); err != nil {
+		if isNotFoundError(err) {
+			return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("No active facility found with ID %d", id))
+		}
+
+		reqLogger.Error().
+			Err(err).
+			Int("facility_id", id).
+			Msg("failed to archive facility")
+
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to archive facility")
+	}
+
+	reqLogger.Info().
+		Int("facility_id", id).
+		Msg("facility archived from list")
+
+	facility, err := h.dbService.GetFacilityByID(c.UserContext(), id)
+	if err != nil {
+		reqLogger.Error().
+			Err(err).
+			Int("facility_id", id).
+			Msg("failed to reload facility for list row")
+
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve facility")
+	}
+
+	if !isPartial(c) {
+		return redirectWithFlash(c, returnPath(c, "/facilities"), fmt.Sprintf("Facility %s archived", facility.Code))
+	}
+	return renderPartial(c, fiber.StatusOK, "facility_row", facility)
+}
+
+// RestoreRow restores an archived facility and answers with its updated
+// list row, or sends a browser without htmx back to the list
+func (h *FacilityHandler) RestoreRow(c *fiber.Ctx) error {
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "RestoreRow").
+		Logger()
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid facility ID")
+	}
+
+	facility, err := h.dbService.RestoreFacility(c.UserContext(), id)
+	if err != nil {
+		if isNotFoundError(err) {
+			return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("No archived facility found with ID %d", id))
+		}
+
+		reqLogger.Error().
+			Err(err).
+			Int("facility_id", id).
+			Msg("failed to restore facility")
+
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to restore facility")
+	}
+
+	reqLogger.Info().
+		Int("facility_id", facility.ID).
+		Msg("facility restored from list")
+
+	if !isPartial(c) {
+		return redirectWithFlash(c, returnPath(c, "/facilities"), fmt.Sprintf("Facility %s restored", facility.Code))
+	}
+	return renderPartial(c, fiber.StatusOK, "facility_row", facility)
+}
+
+// renderFacilityForm answers a facility form submission, as the form
+// fragment for htmx or as the form's page
+func renderFacilityForm(c *fiber.Ctx, status int, form FacilityForm) error {
+	form.CSRFToken = middleware.CSRFToken(c)
+
+	page := createFacilityPage
+	if form.Facility != nil {
+		page = editFacilityPage
+	}
+	return renderForm(c, status, "facility_form", page, form)
+}
+
+// editFacilityForm returns the edit form for a facility, filled in with its
+// current values
+func editFacilityForm(facility *models.Facility) FacilityForm {
+	minOnDuty := facility.MinOnDuty
+	return FacilityForm{
+		Facility: facility,
+		Version:  facility.Version,
+		Values: models.CreateFacilityParams{
+			Name:      facility.Name,
+			Code:      facility.Code,
+			MinOnDuty: &minOnDuty,
+		},
+	}
+}
+
+// RegisterRoutes registers the facility JSON API routes. Reads address a
+// facility by its code, which people know it by, and writes by its ID, which
+// does not change when the code is edited; the spec documents the split.
 func (h *FacilityHandler) RegisterRoutes(app *fiber.App) {
 	facilities := app.Group("api/v1/facilities")
 	// List all facilities
 	facilities.Get("/", h.ListFacilities)
 	// Create new facility endpoint
 	facilities.Post("/", h.CreateFacility)
-	// Create new facility form
-	facilities.Get("/create", h.ShowCreateForm)
-	// Delete facility by ID
+	// Archive facility by ID
 	facilities.Delete("/:id", h.DeleteFacility)
+	// Restore archived facility
+	facilities.Post("/:id/restore", h.RestoreFacility)
+	// Permanently remove archived facility
+	facilities.Delete("/:id/purge", middleware.RequireAdmin(h.dbService), h.PurgeFacility)
+	// Update facility settings by ID
+	facilities.Put("/:id", h.UpdateFacility)
+	// Get facility by code
+	facilities.Get("/:code", h.GetFacility)
 	// Get controllers at facility
 	facilities.Get("/:code/controllers", h.GetFacilityControllers)
 }
+
+// RegisterPageRoutes registers the facility browser pages and the fragments
+// their forms and list rows swap in
+func (h *FacilityHandler) RegisterPageRoutes(app *fiber.App) {
+	pages := app.Group("/facilities")
+	// List all facilities
+	pages.Get("/", h.ShowFacilityList)
+	// Create a facility from the form fragment
+	pages.Post("/", h.SubmitCreateForm)
+	// Create new facility form, registered before /:code so it is not captured
+	pages.Get("/new", h.ShowCreateForm)
+	// Facility page with its controllers
+	pages.Get("/:code", h.ShowFacility)
+	// Edit form, saved by ID like the API so a code change is not lost
+	pages.Get("/:code/edit", h.ShowEditForm)
+	pages.Put("/:id", h.SubmitEditForm)
+	// Archive and restore from the list, answering with the row fragment
+	pages.Delete("/:id", h.ArchiveRow)
+	pages.Post("/:id/restore", h.RestoreRow)
+}
@@ -0,0 +1,112 @@
+// website/handlers/health.go
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dukerupert/weekend-warrior/db"
+	"github.com/dukerupert/weekend-warrior/pkg/buildinfo"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// ProbePaths are the orchestrator probe endpoints. They are kept out of the
+// request log because they are hit every few seconds.
+var ProbePaths = []string{"/healthz", "/readyz", "/version"}
+
+// readyTimeout bounds the database checks behind /readyz
+const readyTimeout = 2 * time.Second
+
+// HealthHandler serves liveness, readiness and build information
+type HealthHandler struct {
+	dbService *db.Service
+	logger    zerolog.Logger
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(dbService *db.Service) *HealthHandler {
+	return &HealthHandler{
+		dbService: dbService,
+		logger:    log.With().Str("handler", "health").Logger(),
+	}
+}
+
+// Healthz reports that the process is alive
+func (h *HealthHandler) Healthz(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// Readyz reports whether the app can serve traffic: the pool can reach the
+// database and the schema is at the migration version this binary expects
+func (h *HealthHandler) Readyz(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), readyTimeout)
+	defer cancel()
+
+	checks := fiber.Map{}
+	ready := true
+
+	if err := h.dbService.Ping(ctx); err != nil {
+		h.logger.Warn().Err(err).Msg("readiness check failed: database")
+		checks["database"] = err.Error()
+		ready = false
+	} else {
+		checks["database"] = "ok"
+	}
+
+	if ready {
+		if err := h.checkMigrations(ctx); err != nil {
+			h.logger.Warn().Err(err).Msg("readiness check failed: migrations")
+			checks["migrations"] = err.Error()
+			ready = false
+		} else {
+			checks["migrations"] = "ok"
+		}
+	}
+
+	if !ready {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status": "unavailable",
+			"checks": checks,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "ready",
+		"checks": checks,
+	})
+}
+
+// checkMigrations compares the applied migration version with the newest
+// migration shipped in the binary
+func (h *HealthHandler) checkMigrations(ctx context.Context) error {
+	expected, err := db.ExpectedMigrationVersion()
+	if err != nil {
+		return err
+	}
+
+	applied, err := h.dbService.MigrationVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	if applied != expected {
+		return fmt.Errorf("database is at migration %d, expected %d", applied, expected)
+	}
+
+	return nil
+}
+
+// Version reports the build commit, build time and Go version
+func (h *HealthHandler) Version(c *fiber.Ctx) error {
+	return c.JSON(buildinfo.Get())
+}
+
+// RegisterRoutes registers the probe routes
+func (h *HealthHandler) RegisterRoutes(app *fiber.App) {
+	app.Get("/healthz", h.Healthz)
+	app.Get("/readyz", h.Readyz)
+	app.Get("/version", h.Version)
+}
@@ -0,0 +1,130 @@
+// handlers/layout.go
+package handlers
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dukerupert/weekend-warrior/middleware"
+	"github.com/dukerupert/weekend-warrior/website/openapi"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Layout is the template every full page is rendered into
+const Layout = "layouts/main"
+
+// flashCookie carries a message across a redirect to the next page shown
+const flashCookie = "flash"
+
+// headerHXRedirect asks htmx to navigate to another page
+const headerHXRedirect = "HX-Redirect"
+
+// LayoutData returns a middleware binding what the layout shows on every
+// page: the signed-in controller, the section of the site for the
+// navigation, the CSRF token for the row action form, and any flash
+// message left by the previous request. Bound
+// values only reach templates rendered with a fiber.Map, so page handlers
+// pass one. It must run after middleware.Auth.
+func LayoutData() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if strings.HasPrefix(c.Path(), openapi.Prefix) {
+			return c.Next()
+		}
+
+		c.Bind(fiber.Map{
+			"CurrentUser": middleware.CurrentUser(c),
+			"Section":     section(c.Path()),
+			"CSRFToken":   middleware.CSRFToken(c),
+		})
+
+		// The flash is shown once, on the next full page
+		if c.Method() == fiber.MethodGet && !isPartial(c) {
+			if message := takeFlash(c); message != "" {
+				c.Bind(fiber.Map{"Flash": message})
+			}
+		}
+
+		return c.Next()
+	}
+}
+
+// section names the navigation entry a page belongs to
+func section(path string) string {
+	switch {
+	case path == "/":
+		return "calendar"
+	case path == "/year":
+		return "year"
+	case path == "/days-off":
+		return "days-off"
+	case path == "/facilities" || strings.HasPrefix(path, "/facilities/"):
+		return "facilities"
+	case path == "/controllers" || strings.HasPrefix(path, "/controllers/"):
+		return "controllers"
+	default:
+		return ""
+	}
+}
+
+// redirectWithFlash sends the browser to another page of the site, which
+// shows message in its flash area. Requests from htmx are told to navigate
+// with HX-Redirect, since the browser would follow a redirect for them.
+func redirectWithFlash(c *fiber.Ctx, to, message string) error {
+	c.Cookie(&fiber.Cookie{
+		Name:     flashCookie,
+		Value:    url.QueryEscape(message),
+		Path:     "/",
+		Secure:   c.Secure(),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
+	if isPartial(c) {
+		c.Set(headerHXRedirect, to)
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+	return c.Redirect(to, fiber.StatusSeeOther)
+}
+
+// takeFlash returns the pending flash message and clears it
+func takeFlash(c *fiber.Ctx) string {
+	value := c.Cookies(flashCookie)
+	if value == "" {
+		return ""
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     flashCookie,
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		Secure:   c.Secure(),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
+	message, err := url.QueryUnescape(value)
+	if err != nil {
+		return ""
+	}
+	return message
+}
+
+// localPath reports whether a redirect target submitted with a form stays on
+// this site
+func localPath(target string) bool {
+	return strings.HasPrefix(target, "/") &&
+		!strings.HasPrefix(target, "//") &&
+		!strings.Contains(target, `\`)
+}
+
+// returnPath is the page a form was posted from, to go back to once it is
+// handled without htmx. It falls back to fallback when the Referer is
+// missing or names another site.
+func returnPath(c *fiber.Ctx, fallback string) string {
+	referer, err := url.Parse(c.Get(fiber.HeaderReferer))
+	if err != nil || referer.Host != c.Hostname() || !localPath(referer.RequestURI()) {
+		return fallback
+	}
+	return referer.RequestURI()
+}
@@ -0,0 +1,109 @@
+// handlers/partials.go
+package handlers
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+
+	"github.com/dukerupert/weekend-warrior/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Headers exchanged with htmx, which the pages load from
+// static/js/htmx.min.js. Every page still works without it: forms post
+// normally and get a redirect or a full page back.
+const (
+	headerHXRequest  = "HX-Request"
+	headerHXRetarget = "HX-Retarget"
+	headerHXReswap   = "HX-Reswap"
+)
+
+// flashTarget is the element on every page that receives error fragments
+const flashTarget = "#flash"
+
+// isPartial reports whether the request was issued by htmx and expects an
+// HTML fragment rather than a full page
+func isPartial(c *fiber.Ctx) bool {
+	return c.Get(headerHXRequest) == "true"
+}
+
+// renderPartial renders a fragment template on its own, without a layout
+func renderPartial(c *fiber.Ctx, status int, name string, data interface{}) error {
+	c.Vary(headerHXRequest)
+	return c.Status(status).Render("partials/"+name, data, "")
+}
+
+// formPage is the full page a form fragment is shown on
+type formPage struct {
+	name  string
+	title string
+}
+
+// Pages holding a single form, which also answer forms posted without htmx
+var (
+	createFacilityPage   = formPage{name: "facilities/create", title: "Create New Facility"}
+	editFacilityPage     = formPage{name: "facilities/edit", title: "Edit Facility"}
+	createControllerPage = formPage{name: "controllers/manage", title: "Create New Controller"}
+	editControllerPage   = formPage{name: "controllers/manage", title: "Edit Controller"}
+	schedulePage         = formPage{name: "controllers/schedule", title: "Assign Schedule"}
+)
+
+// renderForm answers a form submission. htmx swaps the form fragment in
+// place; a form posted without JavaScript gets the full page instead, with
+// the same errors and messages.
+func renderForm(c *fiber.Ctx, status int, name string, page formPage, form interface{}) error {
+	if isPartial(c) {
+		return renderPartial(c, status, name, form)
+	}
+
+	c.Vary(headerHXRequest)
+	return c.Status(status).Render(page.name, fiber.Map{
+		"Title": page.title,
+		"Form":  form,
+	})
+}
+
+// monthURL links to the current page for another month, keeping the rest of
+// the query string such as the facility
+func monthURL(c *fiber.Ctx, year, month int) string {
+	return linkWith(c, url.Values{
+		"year":  {strconv.Itoa(year)},
+		"month": {strconv.Itoa(month)},
+	})
+}
+
+// yearURL links to the current page for another year, keeping the rest of
+// the query string such as the selected controllers
+func yearURL(c *fiber.Ctx, year int) string {
+	return linkWith(c, url.Values{"year": {strconv.Itoa(year)}})
+}
+
+// linkWith links to the current page with some query parameters replaced
+func linkWith(c *fiber.Ctx, replace url.Values) string {
+	query := url.Values{}
+	c.Request().URI().QueryArgs().VisitAll(func(key, value []byte) {
+		query.Add(string(key), string(value))
+	})
+	for key, values := range replace {
+		query[key] = values
+	}
+
+	return c.Path() + "?" + query.Encode()
+}
+
+// formErrors splits the result of validation.Struct for a form handler.
+// Field errors are returned for the form to show next to its inputs; any
+// other failure is returned as err.
+func formErrors(err error) (validation.FieldErrors, error) {
+	if err == nil {
+		return nil, nil
+	}
+
+	var fields validation.FieldErrors
+	if errors.As(err, &fields) {
+		return fields, nil
+	}
+
+	return nil, err
+}
@@ -1,35 +1,40 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/dukerupert/weekend-warrior/db"
 	"github.com/dukerupert/weekend-warrior/db/models"
+	"github.com/dukerupert/weekend-warrior/middleware"
+	"github.com/dukerupert/weekend-warrior/pkg/validation"
+	"github.com/dukerupert/weekend-warrior/services/calendar"
 	"github.com/gofiber/fiber/v2"
 	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
 )
 
 // ScheduleHandler handles HTTP requests for schedules
 type ScheduleHandler struct {
-	dbService *db.Service
-	logger    zerolog.Logger
+	dbService       *db.Service
+	calendarService *calendar.Service
 }
 
 // NewScheduleHandler creates a new schedule handler
-func NewScheduleHandler(dbService *db.Service) *ScheduleHandler {
+func NewScheduleHandler(dbService *db.Service, calendarService *calendar.Service) *ScheduleHandler {
 	return &ScheduleHandler{
-		dbService: dbService,
-		logger:    log.With().Str("handler", "schedule").Logger(),
+		dbService:       dbService,
+		calendarService: calendarService,
 	}
 }
 
 // CreateSchedule handles POST requests to create a new schedule
 func (h *ScheduleHandler) CreateSchedule(c *fiber.Ctx) error {
 	// Create request-specific logger
-	reqLogger := h.logger.With().
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
 		Str("method", "CreateSchedule").
-		Str("request_id", c.GetRespHeader("X-Request-ID")).
 		Logger()
 
 	reqLogger.Info().Msg("processing create schedule request")
@@ -38,7 +43,6 @@ func (h *ScheduleHandler) CreateSchedule(c *fiber.Ctx) error {
 	if err := c.BodyParser(&params); err != nil {
 		reqLogger.Error().
 			Err(err).
-			Str("body", string(c.Body())).
 			Msg("failed to parse request body")
 
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -47,6 +51,14 @@ func (h *ScheduleHandler) CreateSchedule(c *fiber.Ctx) error {
 		})
 	}
 
+	if err := validation.Struct(params); err != nil {
+		reqLogger.Warn().
+			Err(err).
+			Msg("validation failed")
+
+		return validationFailed(c, err)
+	}
+
 	// Log the parsed parameters
 	reqLogger.Debug().
 		Interface("controller_id", params.ControllerID).
@@ -54,11 +66,32 @@ func (h *ScheduleHandler) CreateSchedule(c *fiber.Ctx) error {
 		Time("anchor", params.Anchor).
 		Msg("attempting to create schedule")
 
-	schedule, err := h.dbService.CreateSchedule(c.Context(), params)
+	schedule, err := h.createSchedule(c.UserContext(), params)
 	if err != nil {
+		if isDuplicateKeyError(err) {
+			reqLogger.Warn().
+				Err(err).
+				Int("controller_id", params.ControllerID).
+				Msg("controller already has a schedule")
+
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":  "Schedule already exists",
+				"detail": fmt.Sprintf("controller ID %d already has a schedule; update it instead", params.ControllerID),
+			})
+		}
+
+		if isForeignKeyError(err) {
+			reqLogger.Warn().
+				Err(err).
+				Int("controller_id", params.ControllerID).
+				Msg("schedule names an unknown controller")
+
+			return validationFailed(c, validation.FieldErrors{"controller_id": "must be an existing controller"})
+		}
+
 		reqLogger.Error().
 			Err(err).
-			Interface("params", params).
+			Int("controller_id", params.ControllerID).
 			Msg("failed to create schedule")
 
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -73,6 +106,7 @@ func (h *ScheduleHandler) CreateSchedule(c *fiber.Ctx) error {
 		Time("created_at", schedule.CreatedAt).
 		Msg("schedule created successfully")
 
+	setETag(c, schedule.Version)
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"data": schedule,
 	})
@@ -81,9 +115,8 @@ func (h *ScheduleHandler) CreateSchedule(c *fiber.Ctx) error {
 // GetSchedule handles GET requests to retrieve a schedule by ID
 func (h *ScheduleHandler) GetSchedule(c *fiber.Ctx) error {
 	// Create request-specific logger
-	reqLogger := h.logger.With().
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
 		Str("method", "GetSchedule").
-		Str("request_id", c.GetRespHeader("X-Request-ID")).
 		Logger()
 
 	reqLogger.Info().Msg("processing get schedule request")
@@ -105,7 +138,7 @@ func (h *ScheduleHandler) GetSchedule(c *fiber.Ctx) error {
 		Int("schedule_id", id).
 		Msg("retrieving schedule")
 
-	schedule, err := h.dbService.GetSchedule(c.Context(), id)
+	schedule, err := h.dbService.GetSchedule(c.UserContext(), id)
 	if err != nil {
 		if isNotFoundError(err) {
 			reqLogger.Warn().
@@ -135,6 +168,7 @@ func (h *ScheduleHandler) GetSchedule(c *fiber.Ctx) error {
 		Time("created_at", schedule.CreatedAt).
 		Msg("schedule retrieved successfully")
 
+	setETag(c, schedule.Version)
 	return c.JSON(fiber.Map{
 		"data": schedule,
 	})
@@ -143,9 +177,8 @@ func (h *ScheduleHandler) GetSchedule(c *fiber.Ctx) error {
 // GetScheduleByController handles GET requests to retrieve a schedule by controller ID
 func (h *ScheduleHandler) GetScheduleByController(c *fiber.Ctx) error {
 	// Create request-specific logger
-	reqLogger := h.logger.With().
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
 		Str("method", "GetScheduleByController").
-		Str("request_id", c.GetRespHeader("X-Request-ID")).
 		Logger()
 
 	reqLogger.Info().Msg("processing get schedule by controller request")
@@ -167,7 +200,7 @@ func (h *ScheduleHandler) GetScheduleByController(c *fiber.Ctx) error {
 		Int("controller_id", controllerID).
 		Msg("retrieving schedule for controller")
 
-	schedule, err := h.dbService.GetScheduleByController(c.Context(), controllerID)
+	schedule, err := h.dbService.GetScheduleByController(c.UserContext(), controllerID)
 	if err != nil {
 		if isNotFoundError(err) {
 			reqLogger.Warn().
@@ -198,6 +231,7 @@ func (h *ScheduleHandler) GetScheduleByController(c *fiber.Ctx) error {
 		Interface("rdos", schedule.RDOs).
 		Msg("schedule retrieved successfully")
 
+	setETag(c, schedule.Version)
 	return c.JSON(fiber.Map{
 		"data": schedule,
 	})
@@ -206,9 +240,8 @@ func (h *ScheduleHandler) GetScheduleByController(c *fiber.Ctx) error {
 // UpdateSchedule handles PUT requests to update an existing schedule
 func (h *ScheduleHandler) UpdateSchedule(c *fiber.Ctx) error {
 	// Create request-specific logger
-	reqLogger := h.logger.With().
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
 		Str("method", "UpdateSchedule").
-		Str("request_id", c.GetRespHeader("X-Request-ID")).
 		Logger()
 
 	reqLogger.Info().Msg("processing update schedule request")
@@ -226,11 +259,21 @@ func (h *ScheduleHandler) UpdateSchedule(c *fiber.Ctx) error {
 		})
 	}
 
+	// The client must name the version it last read
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		reqLogger.Warn().
+			Err(err).
+			Int("schedule_id", id).
+			Msg("update rejected without a usable If-Match header")
+
+		return preconditionError(c, err)
+	}
+
 	var params models.UpdateScheduleParams
 	if err := c.BodyParser(&params); err != nil {
 		reqLogger.Error().
 			Err(err).
-			Str("body", string(c.Body())).
 			Int("schedule_id", id).
 			Msg("failed to parse request body")
 
@@ -240,18 +283,48 @@ func (h *ScheduleHandler) UpdateSchedule(c *fiber.Ctx) error {
 		})
 	}
 
+	if err := validation.Struct(params); err != nil {
+		reqLogger.Warn().
+			Err(err).
+			Int("schedule_id", id).
+			Msg("validation failed")
+
+		return validationFailed(c, err)
+	}
+
 	reqLogger.Debug().
 		Int("schedule_id", id).
 		Interface("rdos", params.RDOs).
 		Time("anchor", params.Anchor).
 		Msg("attempting to update schedule")
 
-	schedule, err := h.dbService.UpdateSchedule(c.Context(), id, params)
+	schedule, err := h.updateSchedule(c.UserContext(), id, version, params)
 	if err != nil {
+		if errors.Is(err, db.ErrStaleVersion) {
+			reqLogger.Warn().
+				Int("schedule_id", id).
+				Int("version", version).
+				Msg("schedule update rejected, version is stale")
+
+			current, getErr := h.dbService.GetSchedule(c.UserContext(), id)
+			if getErr != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error":  "Failed to retrieve schedule",
+					"detail": getErr.Error(),
+				})
+			}
+
+			setETag(c, current.Version)
+			return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+				"error":  "Schedule has changed",
+				"detail": "the schedule was modified by someone else; review the current version and retry",
+				"data":   current,
+			})
+		}
+
 		if isNotFoundError(err) {
 			reqLogger.Warn().
 				Int("schedule_id", id).
-				Interface("params", params).
 				Msg("schedule not found for update")
 
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -263,7 +336,6 @@ func (h *ScheduleHandler) UpdateSchedule(c *fiber.Ctx) error {
 		reqLogger.Error().
 			Err(err).
 			Int("schedule_id", id).
-			Interface("params", params).
 			Msg("failed to update schedule")
 
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -279,6 +351,7 @@ func (h *ScheduleHandler) UpdateSchedule(c *fiber.Ctx) error {
 		Time("anchor", schedule.Anchor).
 		Msg("schedule updated successfully")
 
+	setETag(c, schedule.Version)
 	return c.JSON(fiber.Map{
 		"data": schedule,
 	})
@@ -287,9 +360,8 @@ func (h *ScheduleHandler) UpdateSchedule(c *fiber.Ctx) error {
 // DeleteSchedule handles DELETE requests to remove a schedule
 func (h *ScheduleHandler) DeleteSchedule(c *fiber.Ctx) error {
 	// Create request-specific logger
-	reqLogger := h.logger.With().
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
 		Str("method", "DeleteSchedule").
-		Str("request_id", c.GetRespHeader("X-Request-ID")).
 		Logger()
 
 	reqLogger.Info().Msg("processing delete schedule request")
@@ -311,7 +383,8 @@ func (h *ScheduleHandler) DeleteSchedule(c *fiber.Ctx) error {
 		Int("schedule_id", id).
 		Msg("attempting to delete schedule")
 
-	if err := h.dbService.DeleteSchedule(c.Context(), id); err != nil {
+	schedule, err := h.dbService.DeleteSchedule(c.UserContext(), id)
+	if err != nil {
 		if isNotFoundError(err) {
 			reqLogger.Warn().
 				Int("schedule_id", id).
@@ -338,14 +411,406 @@ func (h *ScheduleHandler) DeleteSchedule(c *fiber.Ctx) error {
 		Int("schedule_id", id).
 		Msg("schedule deleted successfully")
 
+	h.calendarService.InvalidateController(c.UserContext(), schedule.ControllerID)
+
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// previewMonths is how many months of pairs the schedule form previews
+const previewMonths = 3
+
+// ScheduleValues are the fields of the schedule form. RDOs are weekdays
+// from 0 (Sunday) to 6 (Saturday) and Anchor is a YYYY-MM-DD date.
+type ScheduleValues struct {
+	RDOs   []int  `form:"rdos"`
+	Anchor string `form:"anchor"`
+}
+
+// ScheduleForm is the view model of a controller's schedule form
+type ScheduleForm struct {
+	Controller *models.Controller
+	// Schedule is the saved schedule, nil until the controller has one
+	Schedule *models.Schedule
+	Version  int
+	Values   ScheduleValues
+	Errors   validation.FieldErrors
+	// Message is a problem with the whole form, Saved a success notice
+	Message string
+	Saved   string
+	// Preview holds the months of pairs the values produce, drawn by the
+	// same calendar code as the facility calendar
+	Preview []calendar.Calendar
+	// CSRFToken lets the form be posted without htmx
+	CSRFToken string
+}
+
+// WeekdayOption is one choice in a day off select
+type WeekdayOption struct {
+	Value    int
+	Name     string
+	Selected bool
+}
+
+// DayOffOptions lists the weekdays for the day off select at slot, with the
+// form's current choice selected
+func (f ScheduleForm) DayOffOptions(slot int) []WeekdayOption {
+	options := make([]WeekdayOption, 7)
+	for day := range options {
+		options[day] = WeekdayOption{
+			Value:    day,
+			Name:     time.Weekday(day).String(),
+			Selected: slot < len(f.Values.RDOs) && f.Values.RDOs[slot] == day,
+		}
+	}
+	return options
+}
+
+// scheduleForm returns the form for a controller's saved schedule, or for a
+// new one with weekends off from today when there is none
+func scheduleForm(controller *models.Controller, schedule *models.Schedule) ScheduleForm {
+	form := ScheduleForm{Controller: controller, Schedule: schedule}
+	if schedule == nil {
+		form.Values = ScheduleValues{
+			RDOs:   []int{int(time.Saturday), int(time.Sunday)},
+			Anchor: time.Now().Format(time.DateOnly),
+		}
+		return form
+	}
+
+	form.Version = schedule.Version
+	form.Values = ScheduleValues{
+		RDOs:   schedule.RDOs,
+		Anchor: schedule.Anchor.Format(time.DateOnly),
+	}
+	return form
+}
+
+// params converts the form values into schedule parameters. Field errors
+// are returned for the form to show; any other failure is returned as err.
+func (v ScheduleValues) params() (models.UpdateScheduleParams, validation.FieldErrors, error) {
+	params := models.UpdateScheduleParams{RDOs: v.RDOs}
+	if v.Anchor != "" {
+		anchor, err := time.Parse(time.DateOnly, v.Anchor)
+		if err != nil {
+			return params, validation.FieldErrors{"anchor": "must be a date"}, nil
+		}
+		params.Anchor = anchor
+	}
+
+	fields, err := formErrors(validation.Struct(params))
+	if err != nil || fields != nil {
+		return params, fields, err
+	}
+
+	// Pairs are made of the first two days off, so the form asks for two
+	if len(params.RDOs) != 2 {
+		return params, validation.FieldErrors{"rdos": "must name two days"}, nil
+	}
+
+	return params, nil, nil
+}
+
+// ShowScheduleForm renders the schedule form of a controller, filled in with
+// their current schedule and a preview of its pairs
+func (h *ScheduleHandler) ShowScheduleForm(c *fiber.Ctx) error {
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "ShowScheduleForm").
+		Logger()
+
+	controller, err := pageController(c, h.dbService, reqLogger)
+	if err != nil {
+		return err
+	}
+
+	schedule, err := h.pageSchedule(c, controller, reqLogger)
+	if err != nil {
+		return err
+	}
+
+	form := scheduleForm(controller, schedule)
+	form.CSRFToken = middleware.CSRFToken(c)
+	h.preview(c.UserContext(), &form)
+
+	reqLogger.Debug().
+		Int("controller_id", controller.ID).
+		Bool("has_schedule", schedule != nil).
+		Msg("rendering controller schedule form")
+
+	return c.Render(schedulePage.name, fiber.Map{
+		"Title": schedulePage.title,
+		"Form":  form,
+	})
+}
+
+// PreviewSchedule renders the preview fragment for the schedule form's
+// current values without saving them
+func (h *ScheduleHandler) PreviewSchedule(c *fiber.Ctx) error {
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "PreviewSchedule").
+		Logger()
+
+	controller, err := pageController(c, h.dbService, reqLogger)
+	if err != nil {
+		return err
+	}
+
+	form := ScheduleForm{Controller: controller}
+	if err := c.QueryParser(&form.Values); err != nil {
+		reqLogger.Warn().
+			Err(err).
+			Msg("failed to parse schedule preview")
+
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid schedule preview")
+	}
+
+	h.preview(c.UserContext(), &form)
+	return renderPartial(c, fiber.StatusOK, "schedule_preview", form)
+}
+
+// SubmitCreateForm handles the schedule form of a controller without a
+// schedule. It answers with the form fragment: re-rendered with field
+// errors when the input is invalid, or showing the saved schedule. A form
+// posted without htmx is sent back to the schedule page once saved.
+func (h *ScheduleHandler) SubmitCreateForm(c *fiber.Ctx) error {
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "SubmitCreateForm").
+		Logger()
+
+	controller, err := pageController(c, h.dbService, reqLogger)
+	if err != nil {
+		return err
+	}
+
+	form := ScheduleForm{Controller: controller}
+	if err := c.BodyParser(&form.Values); err != nil {
+		reqLogger.Warn().
+			Err(err).
+			Msg("failed to parse schedule form")
+
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid form submission")
+	}
+
+	params, fields, err := form.Values.params()
+	if err != nil {
+		return err
+	}
+	if fields != nil {
+		form.Errors = fields
+		return h.renderScheduleForm(c, fiber.StatusUnprocessableEntity, form)
+	}
+
+	schedule, err := h.createSchedule(c.UserContext(), models.CreateScheduleParams{
+		RDOs:         params.RDOs,
+		Anchor:       params.Anchor,
+		ControllerID: controller.ID,
+	})
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			// Someone else gave the controller a schedule in between
+			current, getErr := h.pageSchedule(c, controller, reqLogger)
+			if getErr != nil {
+				return getErr
+			}
+
+			form = scheduleForm(controller, current)
+			form.Message = "This controller already has a schedule. Review it and save again."
+			return h.renderScheduleForm(c, fiber.StatusConflict, form)
+		}
+
+		reqLogger.Error().
+			Err(err).
+			Int("controller_id", controller.ID).
+			Msg("failed to create schedule from form")
+
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to save schedule")
+	}
+
+	reqLogger.Info().
+		Int("schedule_id", schedule.ID).
+		Int("controller_id", controller.ID).
+		Msg("schedule created from form")
+
+	if !isPartial(c) {
+		return redirectWithFlash(c, fmt.Sprintf("/controllers/%d/schedule", controller.ID), "Schedule saved")
+	}
+
+	form = scheduleForm(controller, schedule)
+	form.Saved = "Schedule saved"
+	return h.renderScheduleForm(c, fiber.StatusCreated, form)
+}
+
+// SubmitEditForm handles the schedule form of a controller who already has
+// a schedule. The form carries the version it was rendered from; when
+// someone else saved in between, the form comes back with the current
+// schedule instead of overwriting it.
+func (h *ScheduleHandler) SubmitEditForm(c *fiber.Ctx) error {
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "SubmitEditForm").
+		Logger()
+
+	controller, err := pageController(c, h.dbService, reqLogger)
+	if err != nil {
+		return err
+	}
+
+	previous, err := h.pageSchedule(c, controller, reqLogger)
+	if err != nil {
+		return err
+	}
+	if previous == nil {
+		return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("No schedule found for controller %s", controller.Name))
+	}
+
+	form := ScheduleForm{Controller: controller, Schedule: previous}
+	if err := c.BodyParser(&form.Values); err != nil {
+		reqLogger.Warn().
+			Err(err).
+			Msg("failed to parse schedule form")
+
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid form submission")
+	}
+
+	form.Version, err = strconv.Atoi(c.FormValue("version"))
+	if err != nil || form.Version <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "The form is missing the schedule version; reload the page")
+	}
+
+	params, fields, err := form.Values.params()
+	if err != nil {
+		return err
+	}
+	if fields != nil {
+		form.Errors = fields
+		return h.renderScheduleForm(c, fiber.StatusUnprocessableEntity, form)
+	}
+
+	schedule, err := h.updateSchedule(c.UserContext(), previous.ID, form.Version, params)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrStaleVersion):
+			current, getErr := h.pageSchedule(c, controller, reqLogger)
+			if getErr != nil {
+				return getErr
+			}
+
+			form = scheduleForm(controller, current)
+			form.Message = "This schedule was changed by someone else. Review the current days off and save again."
+			return h.renderScheduleForm(c, fiber.StatusPreconditionFailed, form)
+		case isNotFoundError(err):
+			return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("No schedule found for controller %s", controller.Name))
+		}
+
+		reqLogger.Error().
+			Err(err).
+			Int("schedule_id", previous.ID).
+			Msg("failed to update schedule from form")
+
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to save schedule")
+	}
+
+	reqLogger.Info().
+		Int("schedule_id", schedule.ID).
+		Int("controller_id", controller.ID).
+		Msg("schedule updated from form")
+
+	if !isPartial(c) {
+		return redirectWithFlash(c, fmt.Sprintf("/controllers/%d/schedule", controller.ID), "Schedule saved")
+	}
+
+	form = scheduleForm(controller, schedule)
+	form.Saved = "Schedule saved"
+	return h.renderScheduleForm(c, fiber.StatusOK, form)
+}
+
+// pageSchedule loads the schedule of a controller for a browser page, or nil
+// when they have none
+func (h *ScheduleHandler) pageSchedule(c *fiber.Ctx, controller *models.Controller, reqLogger zerolog.Logger) (*models.Schedule, error) {
+	schedule, err := h.dbService.GetScheduleByController(c.UserContext(), controller.ID)
+	if err != nil {
+		if isNotFoundError(err) {
+			return nil, nil
+		}
+
+		reqLogger.Error().
+			Err(err).
+			Int("controller_id", controller.ID).
+			Msg("failed to retrieve schedule")
+
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve schedule")
+	}
+
+	return schedule, nil
+}
+
+// preview fills in the months of pairs the form's values produce, starting
+// from the later of this month and the anchor's month. It stays empty while
+// the values are invalid.
+func (h *ScheduleHandler) preview(ctx context.Context, form *ScheduleForm) {
+	form.Preview = nil
+
+	params, fields, err := form.Values.params()
+	if err != nil || fields != nil {
+		return
+	}
+
+	start := time.Now()
+	if params.Anchor.After(start) {
+		start = params.Anchor
+	}
+
+	schedules := []models.ControllerSchedule{{
+		Controller: *form.Controller,
+		Schedule:   models.Schedule{RDOs: params.RDOs, Anchor: params.Anchor},
+	}}
+	for _, month := range h.calendarService.GenerateMonths(ctx, start.Year(), int(start.Month()), previewMonths, schedules) {
+		form.Preview = append(form.Preview, month...)
+	}
+}
+
+// renderScheduleForm answers a schedule form submission with its preview,
+// as the form fragment for htmx or as the form's page
+func (h *ScheduleHandler) renderScheduleForm(c *fiber.Ctx, status int, form ScheduleForm) error {
+	form.CSRFToken = middleware.CSRFToken(c)
+	h.preview(c.UserContext(), &form)
+	return renderForm(c, status, "schedule_form", schedulePage, form)
+}
+
+// createSchedule saves a new schedule and drops the cached calendar months
+// it changes. The API and the schedule form both save through it.
+func (h *ScheduleHandler) createSchedule(ctx context.Context, params models.CreateScheduleParams) (*models.Schedule, error) {
+	schedule, err := h.dbService.CreateSchedule(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	h.calendarService.InvalidateController(ctx, schedule.ControllerID)
+	return schedule, nil
+}
+
+// updateSchedule saves changes to a schedule still at version and drops the
+// cached calendar months it changes
+func (h *ScheduleHandler) updateSchedule(ctx context.Context, id, version int, params models.UpdateScheduleParams) (*models.Schedule, error) {
+	schedule, err := h.dbService.UpdateSchedule(ctx, id, version, params)
+	if err != nil {
+		return nil, err
+	}
+
+	h.calendarService.InvalidateController(ctx, schedule.ControllerID)
+	return schedule, nil
+}
+
 // RegisterRoutes registers all schedule routes
 func (h *ScheduleHandler) RegisterRoutes(app *fiber.App) {
 	schedules := app.Group("api/v1/schedules")
 	// Create new schedule
 	schedules.Post("/", h.CreateSchedule)
+	// Find the days a group of controllers are off together; registered
+	// before /:id so it is not captured as an ID
+	schedules.Get("/common-days-off", h.GetCommonDaysOff)
 	// Get schedule by ID
 	schedules.Get("/:id", h.GetSchedule)
 	// Update schedule by ID
@@ -357,4 +822,21 @@ func (h *ScheduleHandler) RegisterRoutes(app *fiber.App) {
 	controllers := schedules.Group("/controller")
 	// Get schedule by controller ID
 	controllers.Get("/:id", h.GetScheduleByController)
+	// Find colleagues to trade days off with for a date
+	controllers.Get("/:id/swap-partners", h.GetSwapPartners)
+}
+
+// RegisterPageRoutes registers the common days off page, the swap partner
+// page, and the schedule form of the controller pages with the fragments it
+// swaps in
+func (h *ScheduleHandler) RegisterPageRoutes(app *fiber.App) {
+	app.Get("/days-off", h.ShowCommonDaysOff)
+	app.Get("/controllers/:id/swaps", h.ShowSwapPartners)
+
+	pages := app.Group("/controllers/:id/schedule")
+
+	pages.Get("/", h.ShowScheduleForm)
+	pages.Post("/", h.SubmitCreateForm)
+	pages.Put("/", h.SubmitEditForm)
+	pages.Get("/preview", h.PreviewSchedule)
 }
@@ -0,0 +1,264 @@
+// handlers/swaps.go
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dukerupert/weekend-warrior/db/models"
+	"github.com/dukerupert/weekend-warrior/pkg/validation"
+	"github.com/dukerupert/weekend-warrior/services/calendar"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+)
+
+// Limits on one swap partner search
+const (
+	defaultSwapWithin = 28
+	maxSwapWithin     = 90
+)
+
+// SwapPartnersQuery is the query string of a swap partner search
+type SwapPartnersQuery struct {
+	// Date is the YYYY-MM-DD day the controller wants off
+	Date string `query:"date"`
+	// Within is how many days from today their own days off are offered,
+	// defaultSwapWithin when 0
+	Within int `query:"within"`
+	// MinOnDuty is how many controllers at the facility must be working a
+	// day for it to be traded. It can only raise the facility's own
+	// minimum.
+	MinOnDuty int `query:"min_on_duty"`
+}
+
+// SwapPartners is the result of a swap partner search
+type SwapPartners struct {
+	Controller models.Controller        `json:"controller"`
+	Date       time.Time                `json:"date"`
+	From       time.Time                `json:"from"`
+	To         time.Time                `json:"to"`
+	MinOnDuty  int                      `json:"min_on_duty"`
+	Candidates []calendar.SwapCandidate `json:"candidates"`
+}
+
+// SwapPartnersPage is the view model of the swap partner page
+type SwapPartnersPage struct {
+	Controller *models.Controller
+	Query      SwapPartnersQuery
+	Errors     validation.FieldErrors
+	// Result is nil until a search has run
+	Result *SwapPartners
+}
+
+// GetSwapPartners handles GET requests for the colleagues a controller
+// could trade days off with to get a date off
+func (h *ScheduleHandler) GetSwapPartners(c *fiber.Ctx) error {
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "GetSwapPartners").
+		Logger()
+
+	controllerID, err := c.ParamsInt("id")
+	if err != nil {
+		reqLogger.Error().
+			Err(err).
+			Str("controller_id_raw", c.Params("id")).
+			Msg("invalid controller ID format")
+
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Invalid controller ID",
+			"detail": err.Error(),
+		})
+	}
+
+	var query SwapPartnersQuery
+	if err := c.QueryParser(&query); err != nil {
+		reqLogger.Warn().
+			Err(err).
+			Msg("failed to parse swap partners query")
+
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "Invalid query",
+			"detail": err.Error(),
+		})
+	}
+
+	controller, err := h.dbService.GetControllerByID(c.UserContext(), controllerID)
+	if err != nil {
+		if isNotFoundError(err) {
+			reqLogger.Warn().
+				Int("controller_id", controllerID).
+				Msg("controller not found")
+
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":  "Controller not found",
+				"detail": fmt.Sprintf("no controller found with ID %d", controllerID),
+			})
+		}
+
+		reqLogger.Error().
+			Err(err).
+			Int("controller_id", controllerID).
+			Msg("failed to retrieve controller")
+
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to retrieve controller",
+			"detail": err.Error(),
+		})
+	}
+
+	result, err := h.swapPartners(c.UserContext(), controller, query)
+	if err != nil {
+		var fields validation.FieldErrors
+		if errors.As(err, &fields) {
+			reqLogger.Warn().
+				Err(err).
+				Msg("validation failed")
+
+			return validationFailed(c, err)
+		}
+
+		reqLogger.Error().
+			Err(err).
+			Int("controller_id", controllerID).
+			Msg("failed to find swap partners")
+
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to find swap partners",
+			"detail": err.Error(),
+		})
+	}
+
+	reqLogger.Info().
+		Int("controller_id", controllerID).
+		Int("candidate_count", len(result.Candidates)).
+		Msg("swap partners found")
+
+	return c.JSON(fiber.Map{
+		"data": result,
+	})
+}
+
+// ShowSwapPartners renders the swap partner page of a controller. The
+// search runs once a date is picked; invalid searches come back with field
+// errors.
+func (h *ScheduleHandler) ShowSwapPartners(c *fiber.Ctx) error {
+	// Create request-specific logger
+	reqLogger := zerolog.Ctx(c.UserContext()).With().
+		Str("method", "ShowSwapPartners").
+		Logger()
+
+	controller, err := pageController(c, h.dbService, reqLogger)
+	if err != nil {
+		return err
+	}
+
+	page := SwapPartnersPage{Controller: controller}
+	if err := c.QueryParser(&page.Query); err != nil {
+		reqLogger.Warn().
+			Err(err).
+			Msg("failed to parse swap partners query")
+
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid search")
+	}
+
+	status := fiber.StatusOK
+	if page.Query.Date != "" {
+		page.Result, err = h.swapPartners(c.UserContext(), controller, page.Query)
+		if page.Errors, err = formErrors(err); err != nil {
+			reqLogger.Error().
+				Err(err).
+				Int("controller_id", controller.ID).
+				Msg("failed to find swap partners")
+
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to find swap partners")
+		}
+		if page.Errors != nil {
+			status = fiber.StatusUnprocessableEntity
+		}
+	}
+	if page.Query.Within == 0 {
+		page.Query.Within = defaultSwapWithin
+	}
+
+	return c.Status(status).Render("controllers/swaps", fiber.Map{
+		"Title": "Swap Partners",
+		"Page":  page,
+	})
+}
+
+// swapPartners validates a swap partner search for controller and runs it
+// against the schedules of their facility. Problems with the query are
+// returned as validation.FieldErrors.
+func (h *ScheduleHandler) swapPartners(ctx context.Context, controller *models.Controller, query SwapPartnersQuery) (*SwapPartners, error) {
+	fields := validation.FieldErrors{}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	date, dateErr := parseDate(query.Date)
+	switch {
+	case dateErr != "":
+		fields["date"] = dateErr
+	case date.Before(today):
+		fields["date"] = "must not be in the past"
+	}
+
+	within := query.Within
+	if within == 0 {
+		within = defaultSwapWithin
+	}
+	if within < 1 || within > maxSwapWithin {
+		fields["within"] = fmt.Sprintf("must be between 1 and %d days", maxSwapWithin)
+	}
+
+	if query.MinOnDuty < 0 {
+		fields["min_on_duty"] = "must not be negative"
+	}
+
+	if len(fields) > 0 {
+		return nil, fields
+	}
+
+	facility, err := h.dbService.GetFacilityByID(ctx, controller.FacilityID)
+	if err != nil {
+		return nil, err
+	}
+	minOnDuty := facility.MinOnDuty
+	if query.MinOnDuty > minOnDuty {
+		minOnDuty = query.MinOnDuty
+	}
+
+	schedules, err := h.dbService.GetFacilitySchedules(ctx, controller.FacilityID)
+	if err != nil {
+		return nil, err
+	}
+
+	var requester *models.ControllerSchedule
+	for i := range schedules {
+		if schedules[i].Controller.ID == controller.ID {
+			requester = &schedules[i]
+			break
+		}
+	}
+	if requester == nil {
+		return nil, validation.FieldErrors{"id": "must name an active controller with a schedule"}
+	}
+
+	if off, _ := h.calendarService.DayOff(requester.Schedule, date); off {
+		return nil, validation.FieldErrors{"date": "is already a day off"}
+	}
+
+	// Days off on offer start tomorrow
+	result := &SwapPartners{
+		Controller: requester.Controller,
+		Date:       date,
+		From:       today.AddDate(0, 0, 1),
+		To:         today.AddDate(0, 0, within),
+		MinOnDuty:  minOnDuty,
+	}
+	result.Candidates = h.calendarService.SwapCandidates(ctx, *requester, schedules, date, result.From, result.To, minOnDuty)
+	return result, nil
+}
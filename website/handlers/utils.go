@@ -5,6 +5,8 @@ import (
 	"errors"
 	"strings"
 
+	"github.com/dukerupert/weekend-warrior/pkg/validation"
+	"github.com/gofiber/fiber/v2"
 	"github.com/jackc/pgx/v5"
 )
 
@@ -46,3 +48,21 @@ func isForeignKeyError(err error) bool {
 	return strings.Contains(errMsg, "foreign key constraint") ||
 		strings.Contains(errMsg, "violates foreign key constraint")
 }
+
+// validationFailed responds with field-level errors from validation.Struct,
+// keyed by JSON field name so forms can show them next to their inputs
+func validationFailed(c *fiber.Ctx, err error) error {
+	var fields validation.FieldErrors
+	if !errors.As(err, &fields) {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":  "Failed to validate request",
+			"detail": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+		"error":  "Validation failed",
+		"detail": fields.Error(),
+		"fields": fields,
+	})
+}
@@ -0,0 +1,106 @@
+// website/openapi/openapi.go
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Prefix is the part of the route table described by the spec
+const Prefix = "/api/v1"
+
+//go:embed openapi.json
+var spec []byte
+
+//go:embed docs.html
+var docs []byte
+
+// Spec returns the embedded OpenAPI 3 document
+func Spec() []byte {
+	return spec
+}
+
+// RegisterRoutes serves the spec and the bundled documentation UI
+func RegisterRoutes(app *fiber.App) {
+	api := app.Group(Prefix)
+
+	api.Get("/openapi.json", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+		return c.Send(spec)
+	})
+
+	api.Get("/docs", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		return c.Send(docs)
+	})
+}
+
+var (
+	fiberParam   = regexp.MustCompile(`:[^/]+`)
+	openapiParam = regexp.MustCompile(`\{[^/}]+\}`)
+)
+
+// normalize reduces a Fiber or OpenAPI path to a form where only the
+// positions of parameters matter, not their names
+func normalize(path string) string {
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	path = fiberParam.ReplaceAllString(path, "{}")
+	return openapiParam.ReplaceAllString(path, "{}")
+}
+
+// Verify compares the registered routes under Prefix with the operations in
+// the spec. It returns an error listing every route that is not documented
+// and every documented operation that has no route.
+func Verify(routes []fiber.Route) error {
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return fmt.Errorf("error parsing OpenAPI spec: %w", err)
+	}
+
+	documented := map[string]bool{}
+	for path, item := range doc.Paths {
+		for method := range item {
+			switch method {
+			case "get", "put", "post", "delete", "patch", "options":
+				documented[strings.ToUpper(method)+" "+normalize(path)] = true
+			}
+		}
+	}
+
+	registered := map[string]bool{}
+	for _, route := range routes {
+		if route.Method == fiber.MethodHead || !strings.HasPrefix(route.Path, Prefix) {
+			continue
+		}
+		registered[route.Method+" "+normalize(route.Path)] = true
+	}
+
+	var problems []string
+	for op := range registered {
+		if !documented[op] {
+			problems = append(problems, "route not in spec: "+op)
+		}
+	}
+	for op := range documented {
+		if !registered[op] {
+			problems = append(problems, "spec operation has no route: "+op)
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("OpenAPI spec and routes disagree:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	return nil
+}
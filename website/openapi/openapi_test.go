@@ -0,0 +1,37 @@
+package openapi_test
+
+import (
+	"testing"
+
+	"github.com/dukerupert/weekend-warrior/website/handlers"
+	"github.com/dukerupert/weekend-warrior/website/openapi"
+	"github.com/gofiber/fiber/v2"
+)
+
+// TestSpecMatchesRoutes fails when an API route is added, removed or moved
+// without the same change to openapi.json, or the other way round
+func TestSpecMatchesRoutes(t *testing.T) {
+	app := fiber.New()
+
+	// Routes are only registered, never served, so no services are needed
+	handlers.NewHealthHandler(nil).RegisterRoutes(app)
+	handlers.NewFacilityHandler(nil).RegisterRoutes(app)
+	handlers.NewControllerHandler(nil, nil).RegisterRoutes(app)
+	handlers.NewScheduleHandler(nil, nil).RegisterRoutes(app)
+	openapi.RegisterRoutes(app)
+
+	if err := openapi.Verify(app.GetRoutes(true)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestVerifyReportsDrift makes sure a route missing from the spec is caught
+func TestVerifyReportsDrift(t *testing.T) {
+	app := fiber.New()
+	openapi.RegisterRoutes(app)
+	app.Get(openapi.Prefix+"/undocumented", func(c *fiber.Ctx) error { return nil })
+
+	if err := openapi.Verify(app.GetRoutes(true)); err == nil {
+		t.Fatal("expected an undocumented route to be reported")
+	}
+}
@@ -0,0 +1,151 @@
+// API documentation page served at /api/v1/docs: renders the OpenAPI spec
+// and lets each operation be tried from the browser
+const methods = ['get', 'post', 'put', 'delete', 'patch'];
+let spec = null;
+
+// Follow a local $ref such as #/components/schemas/Controller
+function resolve(node) {
+    while (node && node.$ref) {
+        node = node.$ref.slice(2).split('/').reduce((acc, key) => acc[key], spec);
+    }
+    return node;
+}
+
+// Build an example value from a schema
+function example(schema, depth = 0) {
+    schema = resolve(schema);
+    if (!schema || depth > 6) return null;
+    if (schema.allOf) {
+        return Object.assign({}, ...schema.allOf.map(s => example(s, depth + 1)));
+    }
+    switch (schema.type) {
+        case 'object': {
+            const out = {};
+            Object.entries(schema.properties || {}).forEach(([key, value]) => {
+                out[key] = example(value, depth + 1);
+            });
+            if (schema.additionalProperties) {
+                out.field = example(schema.additionalProperties, depth + 1);
+            }
+            return out;
+        }
+        case 'array':
+            return [example(schema.items, depth + 1)];
+        case 'integer':
+            return schema.minimum || 0;
+        case 'boolean':
+            return false;
+        default:
+            if (schema.format === 'date-time') return '2024-01-01T00:00:00Z';
+            if (schema.format === 'email') return 'controller@example.com';
+            return 'string';
+    }
+}
+
+function el(tag, attrs = {}, ...children) {
+    const node = document.createElement(tag);
+    Object.entries(attrs).forEach(([key, value]) => node.setAttribute(key, value));
+    children.forEach(child => node.append(child));
+    return node;
+}
+
+function renderOperation(path, method, op) {
+    const params = (op.parameters || []).map(resolve);
+    const details = el('details', { class: 'operation' },
+        el('summary', {},
+            el('span', { class: `method ${method}` }, method.toUpperCase()),
+            el('span', { class: 'path' }, path),
+            el('span', { class: 'summary' }, op.summary || '')));
+    const body = el('div', { class: 'body' });
+    details.append(body);
+
+    if (op.description) body.append(el('p', {}, op.description));
+
+    const inputs = {};
+    if (params.length) {
+        const table = el('table', {}, el('tr', {}, el('th', {}, 'Parameter'), el('th', {}, 'In'), el('th', {}, 'Description'), el('th', {}, 'Value')));
+        params.forEach(p => {
+            const input = el('input', { placeholder: p.schema ? p.schema.type : '' });
+            inputs[`${p.in}:${p.name}`] = input;
+            table.append(el('tr', {},
+                el('td', {}, p.name + (p.required ? ' *' : '')),
+                el('td', {}, p.in),
+                el('td', {}, p.description || ''),
+                el('td', {}, input)));
+        });
+        body.append(table);
+    }
+
+    let bodyInput = null;
+    if (op.requestBody) {
+        const schema = op.requestBody.content['application/json'].schema;
+        bodyInput = el('textarea', { rows: 8 });
+        bodyInput.value = JSON.stringify(example(schema), null, 2);
+        body.append(el('h4', {}, 'Request body'), bodyInput);
+    }
+
+    const responses = el('table', {}, el('tr', {}, el('th', {}, 'Status'), el('th', {}, 'Description')));
+    Object.entries(op.responses || {}).forEach(([status, response]) => {
+        response = resolve(response);
+        responses.append(el('tr', {}, el('td', {}, status), el('td', {}, response.description || '')));
+    });
+    body.append(el('h4', {}, 'Responses'), responses);
+
+    const output = el('pre', {}, '');
+    const button = el('button', { type: 'button' }, 'Send request');
+    button.addEventListener('click', async () => {
+        let url = path;
+        const query = new URLSearchParams();
+        const headers = {};
+        params.forEach(p => {
+            const value = inputs[`${p.in}:${p.name}`].value;
+            if (!value) return;
+            if (p.in === 'path') url = url.replace(`{${p.name}}`, encodeURIComponent(value));
+            if (p.in === 'query') query.append(p.name, value);
+            if (p.in === 'header') headers[p.name] = value;
+        });
+        if (query.toString()) url += `?${query}`;
+        if (bodyInput) headers['Content-Type'] = 'application/json';
+
+        try {
+            const response = await fetch(url, {
+                method: method.toUpperCase(),
+                headers: headers,
+                body: bodyInput ? bodyInput.value : undefined,
+            });
+            const text = await response.text();
+            const etag = response.headers.get('ETag');
+            output.textContent = `${response.status} ${response.statusText}${etag ? `\nETag: ${etag}` : ''}\n\n${text}`;
+        } catch (error) {
+            output.textContent = error.message;
+        }
+    });
+    body.append(button, output);
+
+    return details;
+}
+
+async function load() {
+    const response = await fetch('/api/v1/openapi.json');
+    spec = await response.json();
+
+    document.getElementById('title').textContent = `${spec.info.title} ${spec.info.version}`;
+    document.getElementById('description').textContent = spec.info.description || '';
+
+    const container = document.getElementById('operations');
+    (spec.tags || []).forEach(tag => {
+        const section = el('section', {}, el('h2', {}, tag.name));
+        if (tag.description) section.append(el('p', { class: 'description' }, tag.description));
+        Object.entries(spec.paths).forEach(([path, item]) => {
+            methods.forEach(method => {
+                const op = item[method];
+                if (op && (op.tags || []).includes(tag.name)) {
+                    section.append(renderOperation(path, method, op));
+                }
+            });
+        });
+        container.append(section);
+    });
+}
+
+load();
@@ -0,0 +1,50 @@
+// Site behaviour on top of htmx (js/htmx.min.js, vendored with
+// `make htmx`). htmx issues the hx-* requests and swaps the server's
+// fragments; this adds what is particular to this site: the CSRF token on
+// mutating requests, readable messages for JSON error bodies, and
+// data-hotkey shortcuts. Without htmx the same forms and links load whole
+// pages.
+(function () {
+    function csrfToken() {
+        const match = document.cookie.match(/(?:^|;\s*)csrf_=([^;]*)/);
+        return match ? decodeURIComponent(match[1]) : '';
+    }
+
+    // Echo the CSRF cookie so the server knows the request came from a page
+    document.addEventListener('htmx:configRequest', event => {
+        if (event.detail.verb !== 'get') {
+            event.detail.headers['X-CSRF-Token'] = csrfToken();
+        }
+    });
+
+    // Middleware such as CSRF and rate limiting answers in JSON, which is
+    // shown as a message rather than swapped into the page
+    document.addEventListener('htmx:beforeSwap', event => {
+        const xhr = event.detail.xhr;
+        const contentType = xhr.getResponseHeader('Content-Type') || '';
+        if (!xhr.responseText || contentType.startsWith('text/html')) return;
+
+        event.detail.shouldSwap = false;
+        let message = xhr.responseText;
+        try {
+            const data = JSON.parse(message);
+            message = data.detail || data.error || message;
+        } catch (error) {
+            // not JSON, show the body as is
+        }
+        window.alert(message);
+    });
+
+    // Requests that never got an answer
+    document.addEventListener('htmx:sendError', () => {
+        window.alert('The server could not be reached. Check your connection and try again.');
+    });
+
+    // Elements with data-hotkey are clicked when that key is pressed outside
+    // of a form field
+    document.addEventListener('keydown', event => {
+        if (event.target.closest('input, select, textarea')) return;
+        const el = document.querySelector(`[data-hotkey="${event.key}"]`);
+        if (el) el.click();
+    });
+})();
@@ -0,0 +1,134 @@
+// website/website.go
+package website
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/filesystem"
+	"github.com/gofiber/template/html/v2"
+)
+
+// StaticPath is where the static assets are served
+const StaticPath = "/static"
+
+// Cache lifetimes for static assets. Versioned URLs change whenever the file
+// does, so they can be cached for good; anything else is revalidated hourly.
+const (
+	versionedCacheControl   = "public, max-age=31536000, immutable"
+	unversionedCacheControl = "public, max-age=3600"
+	devCacheControl         = "no-cache"
+)
+
+//go:embed views/*.html views/*/*.html
+var views embed.FS
+
+//go:embed static
+var static embed.FS
+
+// Site holds the page templates and static assets, either embedded in the
+// binary or read from a directory on disk during development
+type Site struct {
+	views  fs.FS
+	static fs.FS
+	// fromDisk reloads templates on every render and disables caching
+	fromDisk bool
+	// versions maps a static file to a hash of its contents
+	versions map[string]string
+}
+
+// New returns the site embedded in the binary, or the one in dir (the
+// website directory, e.g. ./website) when dir is set
+func New(dir string) (*Site, error) {
+	site := &Site{versions: map[string]string{}}
+
+	if dir != "" {
+		site.fromDisk = true
+		site.views = os.DirFS(filepath.Join(dir, "views"))
+		site.static = os.DirFS(filepath.Join(dir, "static"))
+		return site, nil
+	}
+
+	var err error
+	if site.views, err = fs.Sub(views, "views"); err != nil {
+		return nil, fmt.Errorf("error opening embedded views: %w", err)
+	}
+	if site.static, err = fs.Sub(static, "static"); err != nil {
+		return nil, fmt.Errorf("error opening embedded static files: %w", err)
+	}
+
+	// Embedded files never change, so hash them once
+	err = fs.WalkDir(site.static, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(site.static, name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		site.versions[name] = hex.EncodeToString(sum[:])[:12]
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error hashing static files: %w", err)
+	}
+
+	return site, nil
+}
+
+// Engine returns the template engine. Templates can link static files with
+// {{asset "css/app.css"}}. When loading from disk, templates are re-read
+// on every render.
+func (s *Site) Engine() *html.Engine {
+	engine := html.NewFileSystem(http.FS(s.views), ".html")
+	engine.AddFunc("asset", s.AssetURL)
+	engine.Reload(s.fromDisk)
+	return engine
+}
+
+// AssetURL returns the URL of a static file, versioned by its contents so
+// browsers fetch it again only when it changes
+func (s *Site) AssetURL(name string) string {
+	url := path.Join(StaticPath, name)
+	if version, ok := s.versions[name]; ok {
+		url += "?v=" + version
+	}
+	return url
+}
+
+// RegisterRoutes serves the static files under StaticPath
+func (s *Site) RegisterRoutes(app *fiber.App) {
+	app.Use(StaticPath, s.cacheControl, filesystem.New(filesystem.Config{
+		Root: http.FS(s.static),
+	}))
+}
+
+// cacheControl sets the cache lifetime of a served static file
+func (s *Site) cacheControl(c *fiber.Ctx) error {
+	if err := c.Next(); err != nil {
+		return err
+	}
+	if c.Response().StatusCode() != fiber.StatusOK {
+		return nil
+	}
+
+	switch {
+	case s.fromDisk:
+		c.Set(fiber.HeaderCacheControl, devCacheControl)
+	case c.Query("v") != "" && c.Query("v") == s.versions[strings.TrimPrefix(c.Path(), StaticPath+"/")]:
+		c.Set(fiber.HeaderCacheControl, versionedCacheControl)
+	default:
+		c.Set(fiber.HeaderCacheControl, unversionedCacheControl)
+	}
+	return nil
+}